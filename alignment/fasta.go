@@ -0,0 +1,111 @@
+/*
+Package intended to read sequence alignments and to map them
+on the tips of trees, so that ancestral states can be reconstructed.
+So far, only FASTA alignments of nucleotides, optionally gzipped.
+*/
+package alignment
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A sequence of a FASTA alignment
+type Record struct {
+	Name        string // Name of the sequence: first word of the header line, without '>'
+	Description string // Rest of the header line, if any
+	Seq         []byte // Sequence, without new lines
+}
+
+// FastaReader reads successive records from a FASTA stream.
+type FastaReader struct {
+	r      *bufio.Reader
+	header string // header of the next record, if already read
+	line   int    // number of lines read
+}
+
+// NewFastaReader returns a new FastaReader reading from r.
+// The input is transparently decompressed if it is gzipped.
+func NewFastaReader(r io.Reader) (*FastaReader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		var gz *gzip.Reader
+		if gz, err = gzip.NewReader(br); err != nil {
+			return nil, err
+		}
+		br = bufio.NewReader(gz)
+	}
+	return &FastaReader{r: br}, nil
+}
+
+// Next returns the next record of the stream.
+// Returns io.EOF if there is no more record.
+func (fr *FastaReader) Next() (rec *Record, err error) {
+	var line []byte
+	for fr.header == "" {
+		if line, err = fr.readLine(); err != nil {
+			return
+		}
+		if len(line) == 0 {
+			continue
+		}
+		if line[0] != '>' {
+			err = fmt.Errorf("FASTA Error: line %d should be a header starting with >", fr.line)
+			return
+		}
+		fr.header = strings.TrimSpace(string(line[1:]))
+		if fr.header == "" {
+			err = fmt.Errorf("FASTA Error: empty sequence name at line %d", fr.line)
+			return
+		}
+	}
+	rec = &Record{Name: fr.header}
+	// The name is cut at the first whitespace, the rest is a description
+	if i := strings.IndexAny(fr.header, " \t"); i >= 0 {
+		rec.Name = fr.header[:i]
+		rec.Description = strings.TrimSpace(fr.header[i+1:])
+	}
+	fr.header = ""
+	var seq bytes.Buffer
+	for {
+		var peek []byte
+		if peek, err = fr.r.Peek(1); err == io.EOF {
+			err = nil
+			break
+		} else if err != nil {
+			return
+		}
+		if peek[0] == '>' {
+			break
+		}
+		if line, err = fr.readLine(); err != nil {
+			if err == io.EOF {
+				err = nil
+				break
+			}
+			return
+		}
+		seq.Write(line)
+	}
+	rec.Seq = seq.Bytes()
+	return
+}
+
+// Reads the next line, without the end of line characters
+// and surrounding spaces.
+func (fr *FastaReader) readLine() ([]byte, error) {
+	line, err := fr.r.ReadBytes('\n')
+	if err == io.EOF && len(line) > 0 {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	fr.line++
+	return bytes.TrimSpace(line), nil
+}
@@ -0,0 +1,62 @@
+package alignment
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testFasta = ">A first sequence\nACGT\nAC\n\n>B\r\nacgtNN\r\n>C\tthird\n--GTAC\n"
+
+func readAll(t *testing.T, r io.Reader) ([]*Record, error) {
+	t.Helper()
+	fr, err := NewFastaReader(r)
+	if err != nil {
+		return nil, err
+	}
+	recs := make([]*Record, 0)
+	for {
+		rec, err := fr.Next()
+		if err == io.EOF {
+			return recs, nil
+		} else if err != nil {
+			return recs, err
+		}
+		recs = append(recs, rec)
+	}
+}
+
+func TestFastaReader(t *testing.T) {
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte(testFasta))
+	w.Close()
+	expected := []*Record{
+		{"A", "first sequence", []byte("ACGTAC")},
+		{"B", "", []byte("acgtNN")},
+		{"C", "third", []byte("--GTAC")},
+	}
+	for _, r := range []io.Reader{strings.NewReader(testFasta), &gz} {
+		recs, err := readAll(t, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(recs, expected) {
+			t.Errorf("Records:")
+			for _, rec := range recs {
+				t.Errorf("  %q %q %q", rec.Name, rec.Description, rec.Seq)
+			}
+		}
+	}
+}
+
+func TestFastaReaderErrors(t *testing.T) {
+	for _, in := range []string{"ACGT\n>A\nACGT\n", ">\nACGT\n", ">A\nACGT\n> \nACGT\n"} {
+		if _, err := readAll(t, strings.NewReader(in)); err == nil {
+			t.Errorf("Reading %q should return an error", in)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package alignment
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/benjamincjackson/gotree/tree"
+)
+
+// State sets of the IUPAC nucleotide codes, indexed by the
+// upper case code. Gaps and unknown nucleotides are coded as
+// any nucleotide. They are shared by all the encoded sequences,
+// and must not be modified.
+var nucleotideStates = map[byte][]byte{
+	'A': []byte("A"),
+	'C': []byte("C"),
+	'G': []byte("G"),
+	'T': []byte("T"),
+	'U': []byte("T"),
+	'R': []byte("AG"),
+	'Y': []byte("CT"),
+	'S': []byte("CG"),
+	'W': []byte("AT"),
+	'K': []byte("GT"),
+	'M': []byte("AC"),
+	'B': []byte("CGT"),
+	'D': []byte("AGT"),
+	'H': []byte("ACT"),
+	'V': []byte("ACG"),
+	'N': []byte("ACGT"),
+	'-': []byte("ACGT"),
+	'?': []byte("ACGT"),
+	'.': []byte("ACGT"),
+}
+
+// Encodes a nucleotide sequence into state sets, one per site, as
+// expected by tree.SetTipStates. Each IUPAC code is encoded as the
+// sorted set of the nucleotides it represents, gaps and unknown
+// nucleotides as any nucleotide. Lower case codes are accepted.
+//
+// The state sets are shared between sites and sequences, and must
+// not be modified.
+func EncodeNucleotides(seq []byte) ([][]byte, error) {
+	states := make([][]byte, len(seq))
+	for i, c := range seq {
+		if c >= 'a' && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		s, ok := nucleotideStates[c]
+		if !ok {
+			return nil, fmt.Errorf("Unknown nucleotide code %q at position %d", seq[i], i+1)
+		}
+		states[i] = s
+	}
+	return states, nil
+}
+
+// Reads a FASTA nucleotide alignment (optionally gzipped) from r, and sets
+// the states of the tips of the tree having the same names as the sequences,
+// i.e. the first words of their headers (see EncodeNucleotides).
+//
+// Returns the sorted names of the tips that are not in the alignment, and
+// the sorted names of the sequences that are not in the tree; they are not
+// considered as errors. Returns an error if the sequences do not all have
+// the same length, if a sequence name is duplicated, or if the tip names
+// of the tree are not unique.
+func SetTipStates(t *tree.Tree, r io.Reader) (missingTips, missingRecords []string, err error) {
+	if err = t.UpdateTipIndex(); err != nil {
+		return
+	}
+	var fr *FastaReader
+	if fr, err = NewFastaReader(r); err != nil {
+		return
+	}
+	found := make(map[string]bool)
+	missingRecords = make([]string, 0)
+	length := -1
+	for {
+		var rec *Record
+		if rec, err = fr.Next(); err == io.EOF {
+			err = nil
+			break
+		} else if err != nil {
+			return
+		}
+		if _, ok := found[rec.Name]; ok {
+			err = fmt.Errorf("Sequence %s is present several times in the alignment", rec.Name)
+			return
+		}
+		if length == -1 {
+			length = len(rec.Seq)
+		} else if len(rec.Seq) != length {
+			err = fmt.Errorf("Sequence %s has length %d, expected %d: sequences are not aligned", rec.Name, len(rec.Seq), length)
+			return
+		}
+		var states [][]byte
+		if states, err = EncodeNucleotides(rec.Seq); err != nil {
+			err = fmt.Errorf("Sequence %s: %v", rec.Name, err)
+			return
+		}
+		if t.SetTipStates(rec.Name, states) != nil {
+			found[rec.Name] = false
+			missingRecords = append(missingRecords, rec.Name)
+		} else {
+			found[rec.Name] = true
+		}
+	}
+	missingTips = make([]string, 0)
+	for _, name := range t.AllTipNames() {
+		if !found[name] {
+			missingTips = append(missingTips, name)
+		}
+	}
+	sort.Strings(missingTips)
+	sort.Strings(missingRecords)
+	return
+}
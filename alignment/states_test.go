@@ -0,0 +1,63 @@
+package alignment
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/benjamincjackson/gotree/newick"
+)
+
+func TestEncodeNucleotides(t *testing.T) {
+	states, err := EncodeNucleotides([]byte("AcgTuRn-"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"A", "C", "G", "T", "T", "AG", "ACGT", "ACGT"}
+	for i, s := range states {
+		if string(s) != expected[i] {
+			t.Errorf("State %d = %q, expected %q", i, s, expected[i])
+		}
+	}
+	if _, err := EncodeNucleotides([]byte("ACXT")); err == nil {
+		t.Errorf("Encoding an unknown code should return an error")
+	}
+}
+
+func TestSetTipStates(t *testing.T) {
+	tests := []struct {
+		fasta          string
+		missingTips    []string
+		missingRecords []string
+		err            bool
+	}{
+		{">A desc\nAC\n>B\nAG\n>C\nTT\n>D\nCC\n", []string{}, []string{}, false},
+		{">A\nAC\n>B\nAG\n>X\nTT\n", []string{"C", "D"}, []string{"X"}, false},
+		{">A\nAC\n>B\nAGT\n", nil, nil, true},
+		{">A\nAC\n>A\nAG\n", nil, nil, true},
+		{">A\nAC\n>B\nAZ\n", nil, nil, true},
+	}
+	for _, test := range tests {
+		tr, err := newick.NewParser(strings.NewReader("((A,B),C,D);")).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		missingTips, missingRecords, err := SetTipStates(tr, strings.NewReader(test.fasta))
+		if (err != nil) != test.err {
+			t.Errorf("SetTipStates(%q): error %v", test.fasta, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if !reflect.DeepEqual(missingTips, test.missingTips) || !reflect.DeepEqual(missingRecords, test.missingRecords) {
+			t.Errorf("SetTipStates(%q): missing tips %v and records %v, expected %v and %v",
+				test.fasta, missingTips, missingRecords, test.missingTips, test.missingRecords)
+		}
+		for _, tip := range tr.Tips() {
+			if tip.Name() == "A" && (len(tip.Upstates) != 2 || string(tip.Upstates[1]) != "C") {
+				t.Errorf("SetTipStates(%q): states of A = %q", test.fasta, tip.Upstates)
+			}
+		}
+	}
+}
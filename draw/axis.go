@@ -0,0 +1,173 @@
+package draw
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"time"
+)
+
+/* Tick of an axis or scale bar */
+type AxisTick struct {
+	X     float64 // Position of the tick, in tree coordinates
+	Label string  // Label centered below the tick, may be empty
+	Mark  bool    // If true, a tick mark is drawn on the axis line
+}
+
+/* Length of a year, used to convert branch lengths to dates */
+const yearDuration = 365.25 * 24 * time.Hour
+
+/*
+Returns the ticks of a scale bar starting at x=0, whose length is a round
+number close to one fifth of maxLength (1, 2 or 5 times a power of 10).
+*/
+func scaleBarTicks(maxLength float64, unit string) (length float64, ticks []AxisTick) {
+	length = niceNumber(maxLength / 5.0)
+	label := fmt.Sprintf("%g", length)
+	if unit != "" {
+		label += " " + unit
+	}
+	ticks = []AxisTick{
+		{0, "", true},
+		{length / 2.0, label, false},
+		{length, "", true},
+	}
+	return
+}
+
+/* Returns the largest number 1, 2 or 5 times a power of 10 lower than or equal to v */
+func niceNumber(v float64) float64 {
+	if v <= 0 {
+		return 1.0
+	}
+	p := math.Pow(10, math.Floor(math.Log10(v)))
+	for _, m := range []float64{5, 2, 1} {
+		if m*p <= v {
+			return m * p
+		}
+	}
+	return p
+}
+
+/* Calendar intervals between ticks of time axes */
+var timeAxisSteps = []struct {
+	years, months, days int
+}{
+	{0, 0, 1}, {0, 0, 2}, {0, 0, 7}, {0, 0, 14},
+	{0, 1, 0}, {0, 2, 0}, {0, 3, 0}, {0, 6, 0},
+	{1, 0, 0}, {2, 0, 0}, {5, 0, 0}, {10, 0, 0}, {20, 0, 0}, {50, 0, 0},
+	{100, 0, 0}, {200, 0, 0}, {500, 0, 0}, {1000, 0, 0},
+}
+
+/* Maximum number of labeled ticks of time axes */
+const maxTimeTicks = 8
+
+/*
+Returns the ticks of a calendar axis going from the root (x=0) to maxLength,
+the position of the most recent sample, whose date is mostRecent. Branch lengths
+are in time units, unitsPerYear giving their number in one year (e.g. 1 for years,
+12 for months, 365.25 for days).
+
+Ticks are placed on calendar boundaries (first day of years, months...), with
+the smallest interval giving at most maxTimeTicks ticks.
+*/
+func timeAxisTicks(maxLength float64, mostRecent time.Time, unitsPerYear float64) []AxisTick {
+	rootDate := yearsBefore(mostRecent, maxLength/unitsPerYear)
+	xpos := func(d time.Time) float64 {
+		return maxLength - yearsBetween(d, mostRecent)*unitsPerYear
+	}
+	ticks := make([]AxisTick, 0)
+	for _, step := range timeAxisSteps {
+		format := "2006"
+		if step.days > 0 {
+			format = "2006-01-02"
+		} else if step.months > 0 {
+			format = "2006-01"
+		}
+		// First calendar boundary after the root date
+		var d time.Time
+		switch {
+		case step.days > 0:
+			d = time.Date(rootDate.Year(), rootDate.Month(), rootDate.Day(), 0, 0, 0, 0, rootDate.Location())
+		case step.months > 0:
+			month := (int(rootDate.Month())-1)/step.months*step.months + 1
+			d = time.Date(rootDate.Year(), time.Month(month), 1, 0, 0, 0, 0, rootDate.Location())
+		default:
+			d = time.Date(rootDate.Year()/step.years*step.years, 1, 1, 0, 0, 0, 0, rootDate.Location())
+		}
+		for d.Before(rootDate) {
+			d = d.AddDate(step.years, step.months, step.days)
+		}
+		ticks = ticks[:0]
+		for ; !d.After(mostRecent) && len(ticks) <= maxTimeTicks; d = d.AddDate(step.years, step.months, step.days) {
+			label := d.Format(format)
+			if format == "2006" {
+				// Years before 1000 are not padded with zeros
+				label = strconv.Itoa(d.Year())
+			}
+			ticks = append(ticks, AxisTick{xpos(d), label, true})
+		}
+		if len(ticks) <= maxTimeTicks {
+			break
+		}
+	}
+	return ticks
+}
+
+/*
+Returns the date that is the given number of years before t. Whole years are
+subtracted as calendar years, so that spans longer than the range of time.Duration
+(about 292 years) do not overflow.
+*/
+func yearsBefore(t time.Time, years float64) time.Time {
+	whole := math.Floor(years)
+	return t.AddDate(-int(whole), 0, 0).Add(-time.Duration((years - whole) * float64(yearDuration)))
+}
+
+/* Returns the number of years from d to t, counting whole calendar years as in yearsBefore */
+func yearsBetween(d, t time.Time) float64 {
+	whole := t.Year() - d.Year()
+	return float64(whole) + float64(t.Sub(d.AddDate(whole, 0, 0)))/float64(yearDuration)
+}
+
+/* Draws a scale bar below the tree coordinate y */
+func drawScaleBar(td TreeDrawer, y, maxLength float64, unit string) {
+	length, ticks := scaleBarTicks(maxLength, unit)
+	td.DrawAxis(0, length, y, ticks, Style{})
+}
+
+/* Draws a time axis going from the root to the most recent sample, below the tree coordinate y */
+func drawTimeAxis(td TreeDrawer, y, maxLength float64, mostRecent time.Time, unitsPerYear float64) {
+	td.DrawAxis(0, maxLength, y, timeAxisTicks(maxLength, mostRecent, unitsPerYear), Style{})
+}
+
+/* Axes drawn below the tree by layouts */
+type axisOptions struct {
+	scaleBar     bool      // Draws a scale bar
+	scaleBarUnit string    // Unit displayed in the scale bar label
+	mostRecent   time.Time // Date of the most recent sample, zero if no time axis
+	unitsPerYear float64   // Number of branch length units in one year
+}
+
+/*
+Draws the scale bar and the time axis, if enabled, below the tree coordinate y.
+maxLength is the distance from the root to the farthest tip.
+Nothing is drawn if branch lengths are not displayed.
+*/
+func (a *axisOptions) draw(td TreeDrawer, y, maxLength float64, hasBranchLengths bool) {
+	if (a.scaleBar || !a.mostRecent.IsZero()) && !hasBranchLengths {
+		log.Print("Branch lengths are not displayed: scale bar and time axis will not be drawn")
+		return
+	}
+	if !a.mostRecent.IsZero() {
+		if a.unitsPerYear <= 0 {
+			log.Print("Number of branch length units per year must be positive: time axis will not be drawn")
+		} else {
+			drawTimeAxis(td, y, maxLength, a.mostRecent, a.unitsPerYear)
+		}
+	}
+	if a.scaleBar {
+		drawScaleBar(td, y, maxLength, a.scaleBarUnit)
+	}
+}
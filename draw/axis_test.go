@@ -0,0 +1,132 @@
+package draw
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNiceNumber(t *testing.T) {
+	tests := []struct {
+		v, expected float64
+	}{
+		{1, 1}, {1.9, 1}, {2, 2}, {4.99, 2}, {5, 5}, {9.9, 5},
+		{0.03, 0.02}, {0.0071, 0.005}, {340, 200}, {0, 1}, {-3, 1},
+	}
+	for _, test := range tests {
+		if got := niceNumber(test.v); !near(got, test.expected) {
+			t.Errorf("niceNumber(%v) = %v, expected %v", test.v, got, test.expected)
+		}
+	}
+}
+
+func TestScaleBarTicks(t *testing.T) {
+	tests := []struct {
+		maxLength float64
+		unit      string
+		length    float64
+		label     string
+	}{
+		{1, "", 0.2, "0.2"},
+		{0.012, "subst./site", 0.002, "0.002 subst./site"},
+		{48, "SNPs", 5, "5 SNPs"},
+	}
+	for _, test := range tests {
+		length, ticks := scaleBarTicks(test.maxLength, test.unit)
+		if !near(length, test.length) {
+			t.Errorf("scaleBarTicks(%v): length %v, expected %v", test.maxLength, length, test.length)
+		}
+		if len(ticks) != 3 || ticks[0].X != 0 || !ticks[0].Mark || !near(ticks[2].X, length) || !ticks[2].Mark {
+			t.Errorf("scaleBarTicks(%v): ticks %v", test.maxLength, ticks)
+			continue
+		}
+		if ticks[1].Label != test.label || ticks[1].Mark || !near(ticks[1].X, length/2) {
+			t.Errorf("scaleBarTicks(%v): label tick %v, expected %q", test.maxLength, ticks[1], test.label)
+		}
+	}
+}
+
+func TestTimeAxisTicks(t *testing.T) {
+	mostRecent := time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		maxLength    float64
+		unitsPerYear float64
+		labels       string
+	}{
+		// Root on 2018-07-01: 9 ticks every 3 months would be too many
+		{2, 1, "2018-07,2019-01,2019-07,2020-01,2020-07"},
+		// Same dates with branch lengths in months
+		{24, 12, "2018-07,2019-01,2019-07,2020-01,2020-07"},
+		{1.5, 1, "2019-01,2019-04,2019-07,2019-10,2020-01,2020-04,2020-07"},
+		{30, 1, "1995,2000,2005,2010,2015,2020"},
+		// Spans longer than time.Duration: root around 20 AD
+		{2000, 1, "500,1000,1500,2000"},
+		{4000, 1, "-1500,-1000,-500,0,500,1000,1500,2000"},
+		{24000, 12, "500,1000,1500,2000"},
+		// 10 ticks every 2 days would be too many
+		{20, 365.25, "2020-06-11,2020-06-18,2020-06-25"},
+	}
+	for _, test := range tests {
+		ticks := timeAxisTicks(test.maxLength, mostRecent, test.unitsPerYear)
+		labels := make([]string, len(ticks))
+		for i, tick := range ticks {
+			labels[i] = tick.Label
+			if tick.X < 0 || tick.X > test.maxLength+1e-6 || (i > 0 && tick.X <= ticks[i-1].X) {
+				t.Errorf("timeAxisTicks(%v, %v): tick %v out of order or out of the axis", test.maxLength, test.unitsPerYear, tick)
+			}
+		}
+		if got := strings.Join(labels, ","); got != test.labels {
+			t.Errorf("timeAxisTicks(%v, %v) = %s, expected %s", test.maxLength, test.unitsPerYear, got, test.labels)
+		}
+	}
+	// The last tick is on the most recent sample, at maxLength
+	ticks := timeAxisTicks(2, mostRecent, 1)
+	if last := ticks[len(ticks)-1]; !near(last.X, 2) {
+		t.Errorf("Tick of the most recent sample at %v, expected 2", last.X)
+	}
+	// Ticks of long spans are placed at their dates: 1000 is 1020.5 years before mostRecent
+	ticks = timeAxisTicks(2000, mostRecent, 1)
+	if x := ticks[1].X; math.Abs(x-979.5) > 0.01 {
+		t.Errorf("Tick of year 1000 at %v, expected 979.5", x)
+	}
+}
+
+func TestLayoutAxes(t *testing.T) {
+	nw := "((A:1,B:1):1,C:0.5);"
+	tests := []struct {
+		branchLengths bool
+		scaleBar      bool
+		mostRecent    time.Time
+		axes          []string
+	}{
+		{true, true, time.Time{}, []string{",0.2 subst.,"}},
+		{true, false, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), []string{"2018-01,2018-07,2019-01,2019-07,2020-01"}},
+		{true, false, time.Time{}, []string{}},
+		// Without branch lengths, the axes make no sense
+		{false, true, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), []string{}},
+	}
+	for i, test := range tests {
+		r := &recorder{}
+		layout := NewNormalLayout(r, test.branchLengths, true, false, false)
+		layout.SetScaleBar(test.scaleBar, "subst.")
+		layout.SetTimeAxis(test.mostRecent, 1)
+		if err := layout.DrawTree(parse(t, nw)); err != nil {
+			t.Fatal(err)
+		}
+		axes := r.ops("axis")
+		if len(axes) != len(test.axes) {
+			t.Errorf("Test %d: %d axes drawn, expected %d", i, len(axes), len(test.axes))
+			continue
+		}
+		for j, a := range axes {
+			if a.text != test.axes[j] {
+				t.Errorf("Test %d: axis %q, expected %q", i, a.text, test.axes[j])
+			}
+			// Axes are drawn below the last tip
+			if a.coords[2] < 2 {
+				t.Errorf("Test %d: axis drawn at y=%v, over the tree", i, a.coords[2])
+			}
+		}
+	}
+}
@@ -12,6 +12,8 @@ type layoutCache struct {
 	curvePaths      []*layoutCurve
 	verticalPaths   []*layoutVLine
 	horizontalPaths []*layoutHLine
+	symbolPoints    []*layoutPoint
+	polygons        []*layoutPolygon
 }
 
 type layoutPoint struct {
@@ -20,24 +22,28 @@ type layoutPoint struct {
 	brAngle float64 // Angle of the incoming branch
 	name    string  // node name
 	comment string  // node comment
+	style   Style   // style of the node
 }
 
 type layoutLine struct {
 	p1      *layoutPoint
 	p2      *layoutPoint
 	support float64
+	style   Style
 }
 
 type layoutVLine struct {
 	x       float64
 	y1, y2  float64
 	support float64
+	style   Style
 }
 
 type layoutHLine struct {
 	x1, x2  float64
 	y       float64
 	support float64
+	style   Style
 }
 
 type layoutCurve struct {
@@ -46,6 +52,12 @@ type layoutCurve struct {
 	radius      float64      // radius of the circle
 	startAngle  float64
 	endAngle    float64
+	style       Style
+}
+
+type layoutPolygon struct {
+	xs, ys []float64 // coordinates of the vertices
+	style  Style
 }
 
 func newLayoutCache() *layoutCache {
@@ -56,6 +68,8 @@ func newLayoutCache() *layoutCache {
 		make([]*layoutCurve, 0),
 		make([]*layoutVLine, 0),
 		make([]*layoutHLine, 0),
+		make([]*layoutPoint, 0),
+		make([]*layoutPolygon, 0),
 	}
 }
 
@@ -82,3 +96,39 @@ func (cache *layoutCache) borders() (xmin, ymin, xmax, ymax float64) {
 	}
 	return
 }
+
+/*
+Transforms the coordinates of the cached objects (except curves): x becomes ax*x+bx and
+y becomes y+by. If ax is negative (mirrored drawing), the angles of the
+points are mirrored as well.
+*/
+func (cache *layoutCache) transform(ax, bx, by float64) {
+	points := make([]*layoutPoint, 0, len(cache.tipLabelPoints)+len(cache.nodePoints)+len(cache.symbolPoints))
+	points = append(points, cache.tipLabelPoints...)
+	points = append(points, cache.nodePoints...)
+	points = append(points, cache.symbolPoints...)
+	for _, line := range cache.branchPaths {
+		points = append(points, line.p1, line.p2)
+	}
+	done := make(map[*layoutPoint]bool, len(points))
+	for _, p := range points {
+		if !done[p] {
+			p.x, p.y = ax*p.x+bx, p.y+by
+			if ax < 0 {
+				p.brAngle = math.Pi - p.brAngle
+			}
+			done[p] = true
+		}
+	}
+	for _, l := range cache.horizontalPaths {
+		l.x1, l.x2, l.y = ax*l.x1+bx, ax*l.x2+bx, l.y+by
+	}
+	for _, l := range cache.verticalPaths {
+		l.x, l.y1, l.y2 = ax*l.x+bx, l.y1+by, l.y2+by
+	}
+	for _, p := range cache.polygons {
+		for i := range p.xs {
+			p.xs[i], p.ys[i] = ax*p.xs[i]+bx, p.ys[i]+by
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package draw
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"github.com/benjamincjackson/gotree/tree"
+)
+
+type circularLayout struct {
+	drawer                 TreeDrawer
+	spread                 float64 // Angle between two consecutive tips
+	center                 float64 // x and y coordinates of the center of the tree
+	hasBranchLengths       bool
+	hasTipLabels           bool
+	hasInternalNodeLabels  bool
+	hasInternalNodeSymbols bool
+	hasNodeComments        bool
+	hasSupport             bool
+	supportCutoff          float64
+	styler                 Styler
+	axes                   axisOptions
+	collapse               CladeCollapse
+	collapsed              map[*tree.Node]int // Collapsed nodes, with their number of tips
+	cache                  *layoutCache
+}
+
+/*
+Circular (radial fan) layout: the root is at the center, tips are evenly
+spread on 360 degrees, and each node is placed at a distance from the center
+equal to its distance to the root. Branches are drawn as lines, and children
+of an internal node are connected by an arc.
+*/
+func NewCircularLayout(td TreeDrawer, withBranchLengths, withTipLabels, withInternalNodeLabel, withSupportCircles bool) TreeLayout {
+	return &circularLayout{
+		td,
+		0.0,
+		0.0,
+		withBranchLengths,
+		withTipLabels,
+		withInternalNodeLabel,
+		false,
+		false,
+		withSupportCircles,
+		0.7,
+		nil,
+		axisOptions{},
+		CladeCollapse{},
+		nil,
+		newLayoutCache(),
+	}
+}
+
+func (layout *circularLayout) SetSupportCutoff(c float64) {
+	layout.supportCutoff = c
+}
+
+func (layout *circularLayout) SetDisplayInternalNodes(s bool) {
+	layout.hasInternalNodeSymbols = s
+}
+
+func (layout *circularLayout) SetDisplayNodeComments(s bool) {
+	layout.hasNodeComments = s
+}
+
+func (layout *circularLayout) SetStyler(s Styler) {
+	layout.styler = s
+}
+
+func (layout *circularLayout) SetScaleBar(show bool, unit string) {
+	layout.axes.scaleBar = show
+	layout.axes.scaleBarUnit = unit
+}
+
+func (layout *circularLayout) SetTimeAxis(mostRecent time.Time, unitsPerYear float64) {
+	log.Print("Time axis is not supported by the circular layout: It will not be drawn")
+}
+
+func (layout *circularLayout) SetHeatmap(m *TipMetadata) {
+	log.Print("Heatmap is not supported by the circular layout: It will not be drawn")
+}
+
+/*
+Collapses clades into wedges, labeled with the name of their root and their number of tips.
+*/
+func (layout *circularLayout) SetCollapse(c CladeCollapse) {
+	layout.collapse = c
+}
+
+/*
+Draw the tree on the specific drawer. Does not close the file. The caller must do it.
+*/
+func (layout *circularLayout) DrawTree(t *tree.Tree) error {
+	var err error = nil
+	root := t.Root()
+	layout.collapsed = layout.collapse.collapsedNodes(t)
+	ntips := nbDisplayedTips(t, layout.collapsed)
+	curNbTips := 0
+	maxLength, maxName := maxLength(t, layout.hasBranchLengths, layout.hasTipLabels, layout.hasNodeComments, layout.collapsed)
+	layout.spread = 2.0 * math.Pi / float64(ntips)
+	layout.center = maxLength
+	layout.drawer.SetMaxValues(2.0*maxLength, 2.0*maxLength, maxName, maxName)
+	layout.drawTreeRecur(root, nil, tree.NIL_SUPPORT, 0, 0, &curNbTips, nodeStyle(layout.styler, root, nil, Style{}))
+	layout.drawTree()
+	layout.axes.draw(layout.drawer, 2.0*maxLength, maxLength, layout.hasBranchLengths)
+	layout.drawer.Write()
+	return err
+}
+
+/*
+Recursive function that draws the tree. Returns the angle of the current node
+*/
+func (layout *circularLayout) drawTreeRecur(n *tree.Node, prev *tree.Node, support, prevDistToRoot, distToRoot float64, curtip *int, style Style) float64 {
+	angle := 0.0
+	nbchild := 0.0
+	if ntips, ok := layout.collapsed[n]; ok {
+		angle = float64(*curtip) * layout.spread
+		nbchild = 1.0
+		depth := cladeDepth(n, prev, layout.hasBranchLengths)
+		// Wedge from the node to an arc at the depth of the clade
+		x, y := layout.polarToCartesian(distToRoot, angle)
+		polygon := &layoutPolygon{[]float64{x}, []float64{y}, style}
+		half := collapsedCladeHeight * layout.spread / 2.0
+		for _, a := range arcAngles(angle-half, angle+half, 8) {
+			x, y = layout.polarToCartesian(distToRoot+depth, a)
+			polygon.xs = append(polygon.xs, x)
+			polygon.ys = append(polygon.ys, y)
+		}
+		layout.cache.polygons = append(layout.cache.polygons, polygon)
+		if layout.hasTipLabels {
+			x, y = layout.polarToCartesian(distToRoot+depth, angle)
+			label := &layoutPoint{x, y, angle, cladeLabel(n, ntips), "", style}
+			layout.cache.tipLabelPoints = append(layout.cache.tipLabelPoints, label)
+		}
+		*curtip++
+	} else if n.Tip() {
+		angle = float64(*curtip) * layout.spread
+		nbchild = 1.0
+		x, y := layout.polarToCartesian(distToRoot, angle)
+		node := &layoutPoint{x, y, angle, n.Name(), n.CommentsString(), style}
+		if layout.hasTipLabels {
+			layout.cache.tipLabelPoints = append(layout.cache.tipLabelPoints, node)
+		}
+		if style.TipSymbol != NoSymbol {
+			layout.cache.symbolPoints = append(layout.cache.symbolPoints, node)
+		}
+		*curtip++
+	} else {
+		minangle := -1.0
+		maxangle := -1.0
+		for i, child := range n.Neigh() {
+			if child != prev {
+				len := n.Edges()[i].Length()
+				supp := n.Edges()[i].Support()
+				if !layout.hasBranchLengths || len == tree.NIL_LENGTH {
+					len = 1.0
+				}
+				cstyle := nodeStyle(layout.styler, child, n.Edges()[i], style)
+				tempangle := layout.drawTreeRecur(child, n, supp, distToRoot, distToRoot+len, curtip, cstyle)
+				if minangle == -1 || minangle > tempangle {
+					minangle = tempangle
+				}
+				if maxangle == -1 || maxangle < tempangle {
+					maxangle = tempangle
+				}
+				angle += tempangle
+				nbchild += 1.0
+			}
+		}
+		angle /= nbchild
+		if distToRoot > 0 {
+			middlex, middley := layout.polarToCartesian(distToRoot, (minangle+maxangle)/2.0)
+			curve := &layoutCurve{
+				&layoutPoint{layout.center, layout.center, 0.0, "", "", style},
+				&layoutPoint{middlex, middley, 0.0, "", "", style},
+				distToRoot,
+				minangle,
+				maxangle,
+				style,
+			}
+			layout.cache.curvePaths = append(layout.cache.curvePaths, curve)
+		}
+		x, y := layout.polarToCartesian(distToRoot, angle)
+		inode := &layoutPoint{x, y, angle, n.Name(), n.CommentsString(), style}
+		layout.cache.nodePoints = append(layout.cache.nodePoints, inode)
+	}
+
+	if prev != nil {
+		x1, y1 := layout.polarToCartesian(prevDistToRoot, angle)
+		x2, y2 := layout.polarToCartesian(distToRoot, angle)
+		line := &layoutLine{
+			&layoutPoint{x1, y1, angle, "", "", style},
+			&layoutPoint{x2, y2, angle, "", "", style},
+			support,
+			style,
+		}
+		layout.cache.branchPaths = append(layout.cache.branchPaths, line)
+	}
+	return angle
+}
+
+// Converts polar coordinates, relative to the center of the tree,
+// to cartesian coordinates
+func (layout *circularLayout) polarToCartesian(radius, angle float64) (x, y float64) {
+	x = layout.center + radius*math.Cos(angle)
+	y = layout.center + radius*math.Sin(angle)
+	return
+}
+
+func (layout *circularLayout) drawTree() {
+	for _, p := range layout.cache.polygons {
+		layout.drawer.DrawPolygon(p.xs, p.ys, p.style)
+	}
+	for _, l := range layout.cache.branchPaths {
+		layout.drawer.DrawLine(l.p1.x, l.p1.y, l.p2.x, l.p2.y, l.style)
+	}
+	for _, c := range layout.cache.curvePaths {
+		layout.drawer.DrawCurve(c.center.x, c.center.y, c.middlepoint.x, c.middlepoint.y, c.radius, c.startAngle, c.endAngle, c.style)
+	}
+	for _, p := range layout.cache.symbolPoints {
+		layout.drawer.DrawSymbol(p.x, p.y, p.style)
+	}
+	if layout.hasTipLabels {
+		for _, p := range layout.cache.tipLabelPoints {
+			if layout.hasNodeComments {
+				layout.drawer.DrawName(p.x, p.y, p.name+p.comment, p.brAngle, p.style)
+			} else {
+				layout.drawer.DrawName(p.x, p.y, p.name, p.brAngle, p.style)
+			}
+		}
+	}
+	if layout.hasInternalNodeLabels {
+		for _, p := range layout.cache.nodePoints {
+			layout.drawer.DrawName(p.x, p.y, p.name, p.brAngle, p.style)
+		}
+	} else if layout.hasNodeComments {
+		for _, p := range layout.cache.nodePoints {
+			layout.drawer.DrawName(p.x, p.y, p.comment, p.brAngle, p.style)
+		}
+	}
+
+	if layout.hasInternalNodeSymbols {
+		for _, p := range layout.cache.nodePoints {
+			layout.drawer.DrawCircle(p.x, p.y, p.style)
+		}
+	}
+	for _, l := range layout.cache.branchPaths {
+		middlex := (l.p1.x + l.p2.x) / 2.0
+		middley := (l.p1.y + l.p2.y) / 2.0
+		if layout.hasSupport && l.support != tree.NIL_SUPPORT && l.support >= layout.supportCutoff {
+			layout.drawer.DrawCircle(middlex, middley, l.style)
+		}
+	}
+}
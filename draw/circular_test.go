@@ -0,0 +1,57 @@
+package draw
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCircularLayout(t *testing.T) {
+	tests := []struct {
+		withLengths bool
+		radius      map[string]float64 // distance of the tip names to the center
+		curveRadius float64
+	}{
+		{true, map[string]float64{"A": 2, "B": 1.5, "C": 2}, 1},
+		{false, map[string]float64{"A": 2, "B": 2, "C": 1}, 1},
+	}
+	for _, test := range tests {
+		r := &recorder{}
+		l := NewCircularLayout(r, test.withLengths, true, false, true)
+		if err := l.DrawTree(parse(t, "((A:1,B:0.5)0.9:1,C:2);")); err != nil {
+			t.Fatal(err)
+		}
+		center := r.maxWidth / 2
+		if !r.written {
+			t.Errorf("Drawer not written")
+		}
+		names := r.names()
+		if len(names) != 3 {
+			t.Errorf("%d names drawn, expected 3", len(names))
+		}
+		// Tips are evenly spread, in order
+		for i, name := range []string{"A", "B", "C"} {
+			c := names[name]
+			dist := math.Hypot(c.coords[0]-center, c.coords[1]-center)
+			if !near(dist, test.radius[name]) {
+				t.Errorf("Lengths %v: tip %s at distance %f, expected %f", test.withLengths, name, dist, test.radius[name])
+			}
+			if angle := float64(i) * 2 * math.Pi / 3; !near(c.coords[2], angle) {
+				t.Errorf("Lengths %v: tip %s at angle %f, expected %f", test.withLengths, name, c.coords[2], angle)
+			}
+		}
+		if n := len(r.ops("line")); n != 4 {
+			t.Errorf("%d branches drawn, expected 4", n)
+		}
+		curves := r.ops("curve")
+		if len(curves) != 1 {
+			t.Fatalf("%d curves drawn, expected 1", len(curves))
+		}
+		if c := curves[0].coords; !near(c[4], test.curveRadius) || !near(c[5], 0) || !near(c[6], 2*math.Pi/3) {
+			t.Errorf("Curve of radius %f from %f to %f", c[4], c[5], c[6])
+		}
+		// Support of the AB branch
+		if n := len(r.ops("circle")); n != 1 {
+			t.Errorf("%d support circles drawn, expected 1", n)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package draw
+
+import (
+	"fmt"
+
+	"github.com/benjamincjackson/gotree/tree"
+)
+
+/*
+Rules to collapse clades into triangles (or wedges in circular layouts),
+labeled with the name of the clade root and its number of tips. Clades
+matching any of the rules are collapsed, the root of the tree never is.
+*/
+type CladeCollapse struct {
+	// Collapses the clades rooted at the nodes for which it returns true
+	Predicate func(n *tree.Node) bool
+	// If > 0: collapses the clades whose incoming branch has a support >= SupportCutoff,
+	// and that do not contain any other internal branch having such support
+	SupportCutoff float64
+	// If > 0: collapses the smallest clades, so that at most MaxTips tips and
+	// collapsed clades are displayed (if possible)
+	MaxTips int
+}
+
+/* Height of collapsed clades, relative to the space of a tip */
+const collapsedCladeHeight = 0.8
+
+/*
+Returns the collapsed nodes of the tree, with the number of tips of their clade.
+Returns nil if no rule is set.
+*/
+func (c *CladeCollapse) collapsedNodes(t *tree.Tree) map[*tree.Node]int {
+	if c.Predicate == nil && c.SupportCutoff <= 0 && c.MaxTips <= 0 {
+		return nil
+	}
+	root := t.Root()
+	ntips := make(map[*tree.Node]int)
+	supported := make(map[*tree.Node]bool)
+	countTipsRecur(root, nil, ntips, supported, c.SupportCutoff)
+
+	collapsed := make(map[*tree.Node]int)
+	c.collapseRulesRecur(root, nil, nil, ntips, supported, collapsed)
+	if c.MaxTips > 0 {
+		// Smallest clade size such that collapsing clades up to that size
+		// displays at most MaxTips tips and clades
+		displayed := make(map[*tree.Node]int)
+		displayedTipsRecur(root, nil, collapsed, displayed)
+		lo, hi := 1, displayed[root]
+		for lo < hi {
+			mid := (lo + hi) / 2
+			if countDisplayedRecur(root, nil, root, mid, displayed) <= c.MaxTips {
+				hi = mid
+			} else {
+				lo = mid + 1
+			}
+		}
+		collapseSizeRecur(root, nil, root, lo, displayed, ntips, collapsed)
+	}
+	return collapsed
+}
+
+/*
+Recursive function that collapses the nodes matching the predicate or the
+support rule, without visiting the clades already collapsed
+*/
+func (c *CladeCollapse) collapseRulesRecur(n, prev *tree.Node, e *tree.Edge, ntips map[*tree.Node]int, supported map[*tree.Node]bool, collapsed map[*tree.Node]int) {
+	if n.Tip() {
+		return
+	}
+	if prev != nil {
+		if (c.Predicate != nil && c.Predicate(n)) ||
+			(c.SupportCutoff > 0 && e.Support() != tree.NIL_SUPPORT && e.Support() >= c.SupportCutoff && !supported[n]) {
+			collapsed[n] = ntips[n]
+			return
+		}
+	}
+	for i, child := range n.Neigh() {
+		if child != prev {
+			c.collapseRulesRecur(child, n, n.Edges()[i], ntips, supported, collapsed)
+		}
+	}
+}
+
+/*
+Recursive function that counts the tips of each clade, and records the clades
+containing an internal branch with support >= cutoff
+*/
+func countTipsRecur(n, prev *tree.Node, ntips map[*tree.Node]int, supported map[*tree.Node]bool, cutoff float64) {
+	if n.Tip() && prev != nil {
+		ntips[n] = 1
+		return
+	}
+	for i, child := range n.Neigh() {
+		if child != prev {
+			countTipsRecur(child, n, ntips, supported, cutoff)
+			ntips[n] += ntips[child]
+			e := n.Edges()[i]
+			if supported[child] || (!child.Tip() && cutoff > 0 && e.Support() != tree.NIL_SUPPORT && e.Support() >= cutoff) {
+				supported[n] = true
+			}
+		}
+	}
+}
+
+/* Recursive function that counts the tips and collapsed clades displayed below each node */
+func displayedTipsRecur(n, prev *tree.Node, collapsed map[*tree.Node]int, displayed map[*tree.Node]int) {
+	if _, ok := collapsed[n]; ok || (n.Tip() && prev != nil) {
+		displayed[n] = 1
+		return
+	}
+	for _, child := range n.Neigh() {
+		if child != prev {
+			displayedTipsRecur(child, n, collapsed, displayed)
+			displayed[n] += displayed[child]
+		}
+	}
+}
+
+/* Number of tips and clades displayed if clades displaying at most size tips are collapsed */
+func countDisplayedRecur(n, prev, root *tree.Node, size int, displayed map[*tree.Node]int) int {
+	if n != root && displayed[n] <= size {
+		return 1
+	}
+	count := 0
+	for _, child := range n.Neigh() {
+		if child != prev {
+			count += countDisplayedRecur(child, n, root, size, displayed)
+		}
+	}
+	return count
+}
+
+/* Recursive function that collapses the largest clades displaying at most size tips */
+func collapseSizeRecur(n, prev, root *tree.Node, size int, displayed map[*tree.Node]int, ntips map[*tree.Node]int, collapsed map[*tree.Node]int) {
+	if n != root && displayed[n] <= size {
+		if displayed[n] > 1 {
+			collapsed[n] = ntips[n]
+		}
+		return
+	}
+	for _, child := range n.Neigh() {
+		if child != prev {
+			collapseSizeRecur(child, n, root, size, displayed, ntips, collapsed)
+		}
+	}
+}
+
+/* Label of a collapsed clade: name of its root node, and number of tips */
+func cladeLabel(n *tree.Node, ntips int) string {
+	if n.Name() != "" {
+		return fmt.Sprintf("%s (%d tips)", n.Name(), ntips)
+	}
+	return fmt.Sprintf("%d tips", ntips)
+}
+
+/* Maximum distance from the node to the tips of its clade */
+func cladeDepth(n, prev *tree.Node, hasBranchLengths bool) float64 {
+	depth := 0.0
+	for i, child := range n.Neigh() {
+		if child != prev {
+			brlen := n.Edges()[i].Length()
+			if brlen == tree.NIL_LENGTH || !hasBranchLengths {
+				brlen = 1.0
+			}
+			if d := brlen + cladeDepth(child, n, hasBranchLengths); d > depth {
+				depth = d
+			}
+		}
+	}
+	return depth
+}
+
+/* Number of tips and collapsed clades displayed */
+func nbDisplayedTips(t *tree.Tree, collapsed map[*tree.Node]int) int {
+	if collapsed == nil {
+		return len(t.Tips())
+	}
+	displayed := make(map[*tree.Node]int)
+	displayedTipsRecur(t.Root(), nil, collapsed, displayed)
+	return displayed[t.Root()]
+}
@@ -0,0 +1,117 @@
+package draw
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/benjamincjackson/gotree/tree"
+)
+
+func TestCollapsedNodes(t *testing.T) {
+	nw := "(((A:1,B:1)ab:1,(C:1,D:1)0.95:1)abcd:1,(E:1,(F:1,G:1)0.5:1)0.99:1,H:1);"
+	tests := []struct {
+		name     string
+		collapse CladeCollapse
+		expected map[string]int // Number of tips of the collapsed clades, by name or support
+	}{
+		{"none", CladeCollapse{}, nil},
+		{"predicate", CladeCollapse{Predicate: func(n *tree.Node) bool { return n.Name() == "ab" || n.Name() == "abcd" }},
+			map[string]int{"abcd": 4}},
+		// EFG contains no internal branch with support >= 0.9, unlike abcd
+		{"support", CladeCollapse{SupportCutoff: 0.9}, map[string]int{"0.95": 2, "0.99": 3}},
+		// The smallest clades are collapsed: ab, CD, EFG and H are displayed
+		{"max tips 4", CladeCollapse{MaxTips: 4}, map[string]int{"ab": 2, "0.95": 2, "0.99": 3}},
+		{"max tips 6", CladeCollapse{MaxTips: 6}, map[string]int{"ab": 2, "0.95": 2, "0.5": 2}},
+		{"max tips 8", CladeCollapse{MaxTips: 8}, map[string]int{}},
+		{"predicate and max tips", CladeCollapse{Predicate: func(n *tree.Node) bool { return n.Name() == "ab" }, MaxTips: 5},
+			map[string]int{"ab": 2, "0.95": 2, "0.5": 2}},
+	}
+	for _, test := range tests {
+		tr := parse(t, nw)
+		collapsed := test.collapse.collapsedNodes(tr)
+		if test.expected == nil {
+			if collapsed != nil {
+				t.Errorf("%s: %d collapsed nodes, expected nil", test.name, len(collapsed))
+			}
+			continue
+		}
+		got := make(map[string]int)
+		for n, ntips := range collapsed {
+			got[nodeKey(n)] = ntips
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s: collapsed %v, expected %v", test.name, got, test.expected)
+		}
+		if test.collapse.MaxTips > 0 && nbDisplayedTips(tr, collapsed) > test.collapse.MaxTips {
+			t.Errorf("%s: %d tips displayed", test.name, nbDisplayedTips(tr, collapsed))
+		}
+	}
+}
+
+/* Name of the node, or support of its incoming branch if it has no name */
+func nodeKey(n *tree.Node) string {
+	if n.Name() != "" {
+		return n.Name()
+	}
+	for _, e := range n.Edges() {
+		if e.Right() == n {
+			return strconv.FormatFloat(e.Support(), 'g', -1, 64)
+		}
+	}
+	return ""
+}
+
+func TestCollapsedLayouts(t *testing.T) {
+	nw := "(((A:1,B:1)ab:1,(C:1,D:2):1):1,E:1);"
+	collapse := CladeCollapse{Predicate: func(n *tree.Node) bool { return n.Name() == "ab" }}
+	layouts := []struct {
+		name   string
+		layout func(TreeDrawer) TreeLayout
+	}{
+		{"normal", func(d TreeDrawer) TreeLayout { return NewNormalLayout(d, true, true, false, false) }},
+		{"circular", func(d TreeDrawer) TreeLayout { return NewCircularLayout(d, true, true, false, false) }},
+	}
+	for _, l := range layouts {
+		r := &recorder{}
+		layout := l.layout(r)
+		layout.SetCollapse(collapse)
+		if err := layout.DrawTree(parse(t, nw)); err != nil {
+			t.Fatal(err)
+		}
+		names := make([]string, 0)
+		for name := range r.names() {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if exp := []string{"C", "D", "E", "ab (2 tips)"}; !reflect.DeepEqual(names, exp) {
+			t.Errorf("%s: names %q, expected %q", l.name, names, exp)
+		}
+		polygons := r.ops("polygon")
+		if len(polygons) != 1 {
+			t.Fatalf("%s: %d polygons, expected 1", l.name, len(polygons))
+		}
+		// The label is drawn at the far end of the clade
+		label := r.names()["ab (2 tips)"]
+		found := false
+		for i := 0; i < len(polygons[0].coords); i += 2 {
+			px, py := polygons[0].coords[i], polygons[0].coords[i+1]
+			if near(px, label.coords[0]) && near(py, label.coords[1]-collapsedCladeHeight/2) {
+				found = true
+			}
+		}
+		if l.name == "normal" && !found {
+			t.Errorf("%s: label at %v, not at the end of the triangle %v", l.name, label.coords, polygons[0].coords)
+		}
+	}
+	// The clade takes the place of a single tip
+	full, collapsed := &recorder{}, &recorder{}
+	NewNormalLayout(full, true, true, false, false).DrawTree(parse(t, nw))
+	layout := NewNormalLayout(collapsed, true, true, false, false)
+	layout.SetCollapse(collapse)
+	layout.DrawTree(parse(t, nw))
+	if collapsed.maxHeight != full.maxHeight-1 {
+		t.Errorf("Max height with a collapsed clade: %v, expected %v", collapsed.maxHeight, full.maxHeight-1)
+	}
+}
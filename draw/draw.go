@@ -3,7 +3,7 @@ Package intended to draw phylogenetic trees on different devices :
  - Terminal,
  - Images (svg, png)
  - ...
-And with different drawing algorithms. So far, only ASCII form in terminal.
+And with different drawing algorithms. So far, ASCII form in terminal, SVG and PNG images.
  - Circular
  - Normal
  - Unrooted
@@ -11,6 +11,8 @@ And with different drawing algorithms. So far, only ASCII form in terminal.
 package draw
 
 import (
+	"time"
+
 	"github.com/benjamincjackson/gotree/tree"
 )
 
@@ -22,13 +24,26 @@ Generic struct to draw on different supports:
 */
 type TreeDrawer interface {
 	SetMaxValues(maxObjectWidth, maxObjectHeight float64, maxNameLength, maxNameHeight int)
-	DrawHLine(x1, x2, y float64)
-	DrawVLine(x, y1, y float64)
-	DrawLine(x1, y1, x2, y2 float64)
-	DrawCurve(centerx, centery float64, middlex, middley float64, radius float64, startAngle, endAngle float64)
-	DrawCircle(x, y float64)
+	DrawHLine(x1, x2, y float64, style Style)
+	DrawVLine(x, y1, y float64, style Style)
+	DrawLine(x1, y1, x2, y2 float64, style Style)
+	DrawCurve(centerx, centery float64, middlex, middley float64, radius float64, startAngle, endAngle float64, style Style)
+	DrawCircle(x, y float64, style Style)
+	/* Draws a filled polygon, given the coordinates of its vertices */
+	DrawPolygon(xs, ys []float64, style Style)
+	/* Draws the tip symbol of the style (if any) */
+	DrawSymbol(x, y float64, style Style)
 	/* angle : angle of the tip incoming branch */
-	DrawName(x, y float64, name string, angle float64)
+	DrawName(x, y float64, name string, angle float64, style Style)
+	/* Draws a horizontal axis (or scale bar) from x1 to x2, below the tree coordinate y.
+	Successive axes are drawn one below the other */
+	DrawAxis(x1, x2, y float64, ticks []AxisTick, style Style)
+	/* Links two facing tips: the line goes from the end of name1, drawn on the right of
+	(x1,y1), to the start of name2, drawn on the left of (x2,y2). Names may be empty */
+	DrawTipLink(x1, y1, x2, y2 float64, name1, name2 string, style Style)
+	/* Draws a legend (a title followed by colored entries) starting at x, below the tree
+	coordinate y and below the axes. Successive legends are drawn one below the other */
+	DrawLegend(x, y float64, title string, entries []LegendEntry)
 	Write()
 	Bounds() (int, int) /* width, height*/
 }
@@ -44,21 +59,33 @@ type TreeLayout interface {
 	SetSupportCutoff(float64)
 	SetDisplayInternalNodes(bool)
 	SetDisplayNodeComments(bool)
+	SetStyler(Styler)
+	SetScaleBar(show bool, unit string)
+	SetTimeAxis(mostRecent time.Time, unitsPerYear float64)
+	SetCollapse(c CladeCollapse)
+	SetHeatmap(m *TipMetadata)
 }
 
-func maxLength(t *tree.Tree, hasBranchLengths, hasTipNames, hasNodeComments bool) (float64, int) {
+func maxLength(t *tree.Tree, hasBranchLengths, hasTipNames, hasNodeComments bool, collapsed map[*tree.Node]int) (float64, int) {
 	maxlength := 0.0
 	curlength := 0.0
 	maxname := 0
 	root := t.Root()
-	maxLengthRecur(root, nil, curlength, &maxlength, &maxname, hasBranchLengths, hasTipNames, hasNodeComments)
+	maxLengthRecur(root, nil, curlength, &maxlength, &maxname, hasBranchLengths, hasTipNames, hasNodeComments, collapsed)
 	return maxlength, maxname
 }
 
-func maxLengthRecur(n *tree.Node, prev *tree.Node, curlength float64, maxlength *float64, maxname *int, hasBranchLengths, hasTipNames, hasNodeComments bool) {
+func maxLengthRecur(n *tree.Node, prev *tree.Node, curlength float64, maxlength *float64, maxname *int, hasBranchLengths, hasTipNames, hasNodeComments bool, collapsed map[*tree.Node]int) {
 	if curlength > *maxlength {
 		*maxlength = curlength
 	}
+	if ntips, ok := collapsed[n]; ok {
+		// Names of the collapsed tips are not displayed, but the clade label is
+		if hasTipNames && len(cladeLabel(n, ntips)) > *maxname {
+			*maxname = len(cladeLabel(n, ntips))
+		}
+		hasTipNames, hasNodeComments, collapsed = false, false, nil
+	}
 	if n.Tip() {
 		if hasTipNames && hasNodeComments {
 			if len(n.Name()+n.CommentsString()) > *maxname {
@@ -80,7 +107,7 @@ func maxLengthRecur(n *tree.Node, prev *tree.Node, curlength float64, maxlength
 			if brlen == tree.NIL_LENGTH || !hasBranchLengths {
 				brlen = 1.0
 			}
-			maxLengthRecur(child, n, curlength+brlen, maxlength, maxname, hasBranchLengths, hasTipNames, hasNodeComments)
+			maxLengthRecur(child, n, curlength+brlen, maxlength, maxname, hasBranchLengths, hasTipNames, hasNodeComments, collapsed)
 		}
 	}
 }
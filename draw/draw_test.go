@@ -0,0 +1,106 @@
+package draw
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/benjamincjackson/gotree/newick"
+	"github.com/benjamincjackson/gotree/tree"
+)
+
+func parse(t *testing.T, nw string) *tree.Tree {
+	t.Helper()
+	tr, err := newick.NewParser(strings.NewReader(nw)).Parse()
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", nw, err)
+	}
+	return tr
+}
+
+/* Drawer call recorded by the recorder */
+type drawCall struct {
+	op     string    // Name of the TreeDrawer method
+	coords []float64 // Coordinates and other numeric arguments
+	text   string    // Name, title or labels
+	style  Style
+}
+
+/* TreeDrawer that records the calls of the layouts */
+type recorder struct {
+	calls                        []drawCall
+	maxWidth, maxHeight          float64
+	maxNameLength, maxNameHeight int
+	written                      bool
+}
+
+func (r *recorder) add(op string, text string, style Style, coords ...float64) {
+	r.calls = append(r.calls, drawCall{op, coords, text, style})
+}
+
+func (r *recorder) SetMaxValues(maxWidth, maxHeight float64, maxNameLength, maxNameHeight int) {
+	r.maxWidth, r.maxHeight, r.maxNameLength, r.maxNameHeight = maxWidth, maxHeight, maxNameLength, maxNameHeight
+}
+func (r *recorder) DrawHLine(x1, x2, y float64, style Style) { r.add("hline", "", style, x1, x2, y) }
+func (r *recorder) DrawVLine(x, y1, y2 float64, style Style) { r.add("vline", "", style, x, y1, y2) }
+func (r *recorder) DrawLine(x1, y1, x2, y2 float64, style Style) {
+	r.add("line", "", style, x1, y1, x2, y2)
+}
+func (r *recorder) DrawCurve(cx, cy, mx, my, radius, start, end float64, style Style) {
+	r.add("curve", "", style, cx, cy, mx, my, radius, start, end)
+}
+func (r *recorder) DrawCircle(x, y float64, style Style) { r.add("circle", "", style, x, y) }
+func (r *recorder) DrawPolygon(xs, ys []float64, style Style) {
+	coords := make([]float64, 0, 2*len(xs))
+	for i := range xs {
+		coords = append(coords, xs[i], ys[i])
+	}
+	r.add("polygon", "", style, coords...)
+}
+func (r *recorder) DrawSymbol(x, y float64, style Style) { r.add("symbol", "", style, x, y) }
+func (r *recorder) DrawName(x, y float64, name string, angle float64, style Style) {
+	r.add("name", name, style, x, y, angle)
+}
+func (r *recorder) DrawAxis(x1, x2, y float64, ticks []AxisTick, style Style) {
+	labels := make([]string, len(ticks))
+	for i, t := range ticks {
+		labels[i] = t.Label
+	}
+	r.add("axis", strings.Join(labels, ","), style, x1, x2, y)
+}
+func (r *recorder) DrawTipLink(x1, y1, x2, y2 float64, name1, name2 string, style Style) {
+	r.add("link", name1+","+name2, style, x1, y1, x2, y2)
+}
+func (r *recorder) DrawLegend(x, y float64, title string, entries []LegendEntry) {
+	labels := make([]string, len(entries))
+	for i, e := range entries {
+		labels[i] = e.Label
+	}
+	r.add("legend", title+":"+strings.Join(labels, ","), Style{}, x, y)
+}
+func (r *recorder) Write()             { r.written = true }
+func (r *recorder) Bounds() (int, int) { return 0, 0 }
+
+/* Recorded calls of the given operation */
+func (r *recorder) ops(op string) []drawCall {
+	calls := make([]drawCall, 0)
+	for _, c := range r.calls {
+		if c.op == op {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+/* Recorded names, indexed by their text */
+func (r *recorder) names() map[string]drawCall {
+	names := make(map[string]drawCall)
+	for _, c := range r.ops("name") {
+		names[c.text] = c
+	}
+	return names
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
@@ -0,0 +1,468 @@
+package draw
+
+import (
+	"math"
+	"sort"
+)
+
+/*
+Figure used by graphical drawers (svg, png): primitives are recorded in tree
+coordinates, and converted to pixel coordinates when the figure is written,
+so that the tree and its labels fit in the drawing area, using the real size
+of the labels.
+*/
+type figure struct {
+	width, height           int // Size of the image, in pixels
+	leftmargin, rightmargin int // Margins, in pixels
+	topmargin, bottommargin int
+	lines                   []figureLine
+	curves                  []figureCurve
+	circles                 []figurePoint
+	symbols                 []figurePoint
+	polygons                []figurePolygon
+	names                   []figureName
+	axes                    []figureAxis
+	links                   []figureLink
+	legends                 []figureLegend
+	axisAligned             bool                                    // HLines or VLines have been drawn
+	measure                 func(name string, size float64) float64 // Width of a label in pixels, for a given font size
+	unit                    float64                                 // Pixels per point
+	fontSize                float64                                 // Font size in pixels, computed by fit
+	xscale, yscale          float64                                 // Pixels per tree unit
+	xoffset, yoffset        float64                                 // Pixel coordinates of tree coordinate 0
+}
+
+type figurePoint struct {
+	x, y  float64
+	style Style
+}
+
+type figureLine struct {
+	x1, y1, x2, y2 float64
+	style          Style
+}
+
+type figureCurve struct {
+	centerx, centery float64
+	radius           float64
+	startAngle       float64
+	endAngle         float64
+	style            Style
+}
+
+type figurePolygon struct {
+	xs, ys []float64
+	style  Style
+}
+
+type figureAxis struct {
+	x1, x2, y float64
+	ticks     []AxisTick
+	style     Style
+}
+
+type figureLink struct {
+	x1, y1, x2, y2 float64
+	name1, name2   string
+	style          Style
+}
+
+type figureLegend struct {
+	x, y    float64
+	title   string
+	entries []LegendEntry
+}
+
+type figureName struct {
+	x, y  float64
+	name  string
+	angle float64
+	style Style
+}
+
+const (
+	maxFontSize  = 12.0 // Maximum font size of the labels, in points
+	minFontSize  = 3.0  // Minimum font size of the labels, in points
+	circleRadius = 2.5  // Radius of the circles, in points
+	symbolRadius = 3.0  // Radius of the tip symbols, in points
+	labelPadding = 3.0  // Space between a node and its label, in points
+	axisFontSize = 10.0 // Font size of the axis labels, in points
+	axisGap      = 8.0  // Space above each axis, in points
+	tickLength   = 4.0  // Length of the axis tick marks, in points
+)
+
+func newFigure(width, height, leftmargin, rightmargin, topmargin, bottommargin int, unit float64, measure func(string, float64) float64) *figure {
+	return &figure{
+		width:        width,
+		height:       height,
+		leftmargin:   leftmargin,
+		rightmargin:  rightmargin,
+		topmargin:    topmargin,
+		bottommargin: bottommargin,
+		lines:        make([]figureLine, 0),
+		curves:       make([]figureCurve, 0),
+		circles:      make([]figurePoint, 0),
+		symbols:      make([]figurePoint, 0),
+		polygons:     make([]figurePolygon, 0),
+		names:        make([]figureName, 0),
+		axes:         make([]figureAxis, 0),
+		links:        make([]figureLink, 0),
+		legends:      make([]figureLegend, 0),
+		measure:      measure,
+		unit:         unit,
+		fontSize:     maxFontSize * unit,
+		xscale:       1.0,
+		yscale:       1.0,
+	}
+}
+
+func (f *figure) drawHLine(x1, x2, y float64, style Style) {
+	f.axisAligned = true
+	f.lines = append(f.lines, figureLine{x1, y, x2, y, style})
+}
+
+func (f *figure) drawVLine(x, y1, y2 float64, style Style) {
+	f.axisAligned = true
+	f.lines = append(f.lines, figureLine{x, y1, x, y2, style})
+}
+
+func (f *figure) drawLine(x1, y1, x2, y2 float64, style Style) {
+	f.lines = append(f.lines, figureLine{x1, y1, x2, y2, style})
+}
+
+func (f *figure) drawCurve(centerx, centery, radius, startAngle, endAngle float64, style Style) {
+	f.curves = append(f.curves, figureCurve{centerx, centery, radius, startAngle, endAngle, style})
+}
+
+func (f *figure) drawCircle(x, y float64, style Style) {
+	f.circles = append(f.circles, figurePoint{x, y, style})
+}
+
+func (f *figure) drawPolygon(xs, ys []float64, style Style) {
+	if len(xs) == len(ys) && len(xs) > 2 {
+		f.polygons = append(f.polygons, figurePolygon{xs, ys, style})
+	}
+}
+
+// Pixel coordinates of the vertices of the polygon
+func (f *figure) polygonPixels(p figurePolygon) [][2]float64 {
+	points := make([][2]float64, len(p.xs))
+	for i := range p.xs {
+		points[i] = [2]float64{f.px(p.xs[i]), f.py(p.ys[i])}
+	}
+	return points
+}
+
+func (f *figure) drawSymbol(x, y float64, style Style) {
+	if style.TipSymbol != NoSymbol {
+		f.symbols = append(f.symbols, figurePoint{x, y, style})
+	}
+}
+
+func (f *figure) drawName(x, y float64, name string, angle float64, style Style) {
+	if name != "" {
+		f.names = append(f.names, figureName{x, y, name, angle, style})
+	}
+}
+
+func (f *figure) drawAxis(x1, x2, y float64, ticks []AxisTick, style Style) {
+	f.axes = append(f.axes, figureAxis{x1, x2, y, ticks, style})
+}
+
+func (f *figure) drawTipLink(x1, y1, x2, y2 float64, name1, name2 string, style Style) {
+	f.links = append(f.links, figureLink{x1, y1, x2, y2, name1, name2, style})
+}
+
+// Pixel coordinates of the ends of the link, after and before the names
+// of the linked tips. Returns false if the names leave no room for the link.
+func (f *figure) linkPixels(l figureLink) (x1, y1, x2, y2 float64, ok bool) {
+	space := func(name string) float64 {
+		if name == "" {
+			return labelPadding * f.unit
+		}
+		return f.measure(name, f.fontSize) + (2.0*labelPadding+symbolRadius)*f.unit
+	}
+	x1, y1 = f.px(l.x1)+space(l.name1), f.py(l.y1)
+	x2, y2 = f.px(l.x2)-space(l.name2), f.py(l.y2)
+	ok = x1 < x2
+	return
+}
+
+// Pixel offsets, below the tree coordinate of the i-th axis, of
+// its line and of the vertical center of its labels
+func (f *figure) axisOffsets(i int) (line, label float64) {
+	height := (axisGap + tickLength + labelPadding + axisFontSize) * f.unit
+	line = float64(i)*height + axisGap*f.unit
+	label = line + (tickLength+labelPadding+axisFontSize/2.0)*f.unit
+	return
+}
+
+func (f *figure) drawLegend(x, y float64, title string, entries []LegendEntry) {
+	f.legends = append(f.legends, figureLegend{x, y, title, entries})
+}
+
+// Pixel offset, below the tree coordinate, of the vertical center of
+// the i-th legend. Legends are drawn below the axes.
+func (f *figure) legendOffset(i int) float64 {
+	line, _ := f.axisOffsets(len(f.axes))
+	return line + (float64(i)*(axisGap+axisFontSize)+axisFontSize/2.0)*f.unit
+}
+
+// Pixel offsets, from the legend anchor, of the boxes and of the labels of
+// its entries, the title being drawn first. Returns also the legend width.
+func (f *figure) legendPositions(l figureLegend) (boxes, labels []float64, width float64) {
+	size := axisFontSize * f.unit
+	x := f.measure(l.title, size) + 2.0*labelPadding*f.unit
+	boxes = make([]float64, len(l.entries))
+	labels = make([]float64, len(l.entries))
+	for i, e := range l.entries {
+		boxes[i] = x
+		x += size + labelPadding*f.unit
+		labels[i] = x
+		x += f.measure(e.Label, size) + 3.0*labelPadding*f.unit
+	}
+	width = x
+	return
+}
+
+// Returns the ticks of the axis whose labels are drawn: labels
+// overlapping the previous drawn label are removed
+func (f *figure) axisLabels(a figureAxis) []AxisTick {
+	labels := make([]AxisTick, 0, len(a.ticks))
+	last := math.Inf(-1)
+	for _, t := range a.ticks {
+		if t.Label == "" {
+			continue
+		}
+		w := f.measure(t.Label, axisFontSize*f.unit)
+		if x := f.px(t.X); x-w/2.0 >= last {
+			labels = append(labels, t)
+			last = x + w/2.0 + labelPadding*f.unit
+		}
+	}
+	return labels
+}
+
+// Space between the anchor of a label and the label, in pixels.
+// Labels of tips having a symbol are drawn after the symbol.
+func (f *figure) namePadding(n figureName) float64 {
+	if n.style.TipSymbol != NoSymbol {
+		return (labelPadding + symbolRadius) * f.unit
+	}
+	return labelPadding * f.unit
+}
+
+// Vertices of the tip symbol centered on (x,y), in pixels.
+// Returns nil for circles.
+func (f *figure) symbolPolygon(p figurePoint) [][2]float64 {
+	x, y := f.px(p.x), f.py(p.y)
+	r := symbolRadius * f.unit
+	switch p.style.TipSymbol {
+	case SquareSymbol:
+		return [][2]float64{{x - r, y - r}, {x + r, y - r}, {x + r, y + r}, {x - r, y + r}}
+	case TriangleSymbol:
+		return [][2]float64{{x, y - r}, {x + r*math.Sqrt(3)/2.0, y + r/2.0}, {x - r*math.Sqrt(3)/2.0, y + r/2.0}}
+	}
+	return nil
+}
+
+// Pixel coordinates of the given tree coordinates
+func (f *figure) px(x float64) float64 {
+	return f.xoffset + x*f.xscale
+}
+
+func (f *figure) py(y float64) float64 {
+	return f.yoffset + y*f.yscale
+}
+
+// Computes the scales and offsets so that everything fits in the image,
+// and the font size so that consecutive labels do not overlap too much.
+//
+// If no axis aligned lines (HLine, VLine) have been drawn (e.g. circular
+// or unrooted layouts), both axes have the same scale.
+func (f *figure) fit() {
+	f.fontSize = maxFontSize * f.unit
+	for i := 0; i < 2; i++ {
+		f.fitScales()
+		f.fitFontSize()
+	}
+	f.fitScales()
+}
+
+// An object to place on one axis: a point at tree coordinate v,
+// extending from lo to hi pixels around it
+type figureExtent struct {
+	v, lo, hi float64
+}
+
+func (f *figure) fitScales() {
+	xs := make([]figureExtent, 0, 2*len(f.lines)+len(f.names))
+	ys := make([]figureExtent, 0, 2*len(f.lines)+len(f.names))
+	for _, l := range f.lines {
+		xs = append(xs, figureExtent{l.x1, 0, 0}, figureExtent{l.x2, 0, 0})
+		ys = append(ys, figureExtent{l.y1, 0, 0}, figureExtent{l.y2, 0, 0})
+	}
+	for _, c := range f.curves {
+		for _, a := range arcAngles(c.startAngle, c.endAngle, 16) {
+			xs = append(xs, figureExtent{c.centerx + c.radius*math.Cos(a), 0, 0})
+			ys = append(ys, figureExtent{c.centery + c.radius*math.Sin(a), 0, 0})
+		}
+	}
+	for _, p := range f.polygons {
+		for i := range p.xs {
+			xs = append(xs, figureExtent{p.xs[i], 0, 0})
+			ys = append(ys, figureExtent{p.ys[i], 0, 0})
+		}
+	}
+	for _, c := range f.circles {
+		r := circleRadius * f.unit
+		xs = append(xs, figureExtent{c.x, -r, r})
+		ys = append(ys, figureExtent{c.y, -r, r})
+	}
+	for _, s := range f.symbols {
+		r := symbolRadius * f.unit
+		xs = append(xs, figureExtent{s.x, -r, r})
+		ys = append(ys, figureExtent{s.y, -r, r})
+	}
+	for i, a := range f.axes {
+		_, label := f.axisOffsets(i)
+		xs = append(xs, figureExtent{a.x1, 0, 0}, figureExtent{a.x2, 0, 0})
+		ys = append(ys, figureExtent{a.y, 0, label + axisFontSize*f.unit/2.0})
+		for _, t := range a.ticks {
+			w := f.measure(t.Label, axisFontSize*f.unit)
+			xs = append(xs, figureExtent{t.X, -w / 2.0, w / 2.0})
+		}
+	}
+	for i, l := range f.legends {
+		_, _, w := f.legendPositions(l)
+		xs = append(xs, figureExtent{l.x, 0, w})
+		ys = append(ys, figureExtent{l.y, 0, f.legendOffset(i) + axisFontSize*f.unit/2.0})
+	}
+	for _, l := range f.links {
+		xs = append(xs, figureExtent{l.x1, 0, 0}, figureExtent{l.x2, 0, 0})
+		ys = append(ys, figureExtent{l.y1, 0, 0}, figureExtent{l.y2, 0, 0})
+	}
+	for _, n := range f.names {
+		xlo, xhi, ylo, yhi := f.nameExtent(n)
+		xs = append(xs, figureExtent{n.x, xlo, xhi})
+		ys = append(ys, figureExtent{n.y, ylo, yhi})
+	}
+	availw := float64(f.width - f.leftmargin - f.rightmargin)
+	availh := float64(f.height - f.topmargin - f.bottommargin)
+	f.xscale = fitScale(xs, availw)
+	f.yscale = fitScale(ys, availh)
+	if !f.axisAligned {
+		f.xscale = math.Min(f.xscale, f.yscale)
+		f.yscale = f.xscale
+	}
+	f.xoffset = float64(f.leftmargin) - minExtent(xs, f.xscale)
+	f.yoffset = float64(f.topmargin) - minExtent(ys, f.yscale)
+}
+
+// Pixel extent of a label around its anchor: it is drawn along the
+// direction of its angle, and centered vertically on it
+func (f *figure) nameExtent(n figureName) (xlo, xhi, ylo, yhi float64) {
+	w := f.measure(n.name, f.fontSize)
+	h := f.fontSize / 2.0
+	cos, sin := math.Cos(n.angle), math.Sin(n.angle)
+	xlo, xhi, ylo, yhi = math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)
+	pad := f.namePadding(n)
+	for _, d := range []float64{pad, pad + w} {
+		for _, o := range []float64{-h, h} {
+			x, y := d*cos-o*sin, d*sin+o*cos
+			xlo, xhi = math.Min(xlo, x), math.Max(xhi, x)
+			ylo, yhi = math.Min(ylo, y), math.Max(yhi, y)
+		}
+	}
+	return
+}
+
+// Computes the font size from the median distance between
+// consecutive labels
+func (f *figure) fitFontSize() {
+	dists := make([]float64, 0, len(f.names))
+	for i := 1; i < len(f.names); i++ {
+		dx := f.px(f.names[i].x) - f.px(f.names[i-1].x)
+		dy := f.py(f.names[i].y) - f.py(f.names[i-1].y)
+		if d := math.Sqrt(dx*dx + dy*dy); d > 0 {
+			dists = append(dists, d)
+		}
+	}
+	if len(dists) == 0 {
+		f.fontSize = maxFontSize * f.unit
+		return
+	}
+	sort.Float64s(dists)
+	f.fontSize = math.Max(minFontSize*f.unit, math.Min(maxFontSize*f.unit, 0.9*dists[len(dists)/2]))
+}
+
+// Returns the largest scale such that all the objects fit in avail pixels
+func fitScale(extents []figureExtent, avail float64) float64 {
+	size := func(s float64) float64 {
+		min, max := math.Inf(1), math.Inf(-1)
+		for _, e := range extents {
+			min = math.Min(min, e.v*s+e.lo)
+			max = math.Max(max, e.v*s+e.hi)
+		}
+		return max - min
+	}
+	if len(extents) == 0 || avail <= 0 {
+		return 1.0
+	}
+	vmin, vmax := math.Inf(1), math.Inf(-1)
+	for _, e := range extents {
+		vmin, vmax = math.Min(vmin, e.v), math.Max(vmax, e.v)
+	}
+	if vmax == vmin {
+		return 1.0
+	}
+	// Scale without taking labels into account
+	hi := avail / (vmax - vmin)
+	if size(0) >= avail {
+		// Labels do not fit anyway
+		return hi / 10.0
+	}
+	lo := 0.0
+	for i := 0; i < 50; i++ {
+		mid := (lo + hi) / 2.0
+		if size(mid) <= avail {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return lo
+}
+
+// Returns the minimum pixel coordinate of the objects, for the given scale
+func minExtent(extents []figureExtent, scale float64) float64 {
+	if len(extents) == 0 {
+		return 0
+	}
+	min := math.Inf(1)
+	for _, e := range extents {
+		min = math.Min(min, e.v*scale+e.lo)
+	}
+	return min
+}
+
+// Returns n+1 angles evenly spaced between start and end
+func arcAngles(start, end float64, n int) []float64 {
+	angles := make([]float64, n+1)
+	for i := 0; i <= n; i++ {
+		angles[i] = start + (end-start)*float64(i)/float64(n)
+	}
+	return angles
+}
+
+/* Returns true if the point is inside the polygon (even-odd rule) */
+func insidePolygon(poly [][2]float64, x, y float64) bool {
+	inside := false
+	for i, j := 0, len(poly)-1; i < len(poly); j, i = i, i+1 {
+		xi, yi, xj, yj := poly[i][0], poly[i][1], poly[j][0], poly[j][1]
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
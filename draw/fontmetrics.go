@@ -0,0 +1,34 @@
+package draw
+
+/*
+Widths of the printable ASCII characters (from ' ' to '~') in the
+Helvetica font, in 1/1000 of the font size (from the Adobe font metrics).
+Used to compute the size of labels in graphical drawers.
+*/
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // ' ' to '/'
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, // '0' to '9'
+	278, 278, 584, 584, 584, 556, 1015, // ':' to '@'
+	667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, // 'A' to 'M'
+	722, 778, 667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, // 'N' to 'Z'
+	278, 278, 278, 469, 556, 333, // '[' to '`'
+	556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, // 'a' to 'm'
+	556, 556, 556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, // 'n' to 'z'
+	334, 260, 334, 584, // '{' to '~'
+}
+
+/* Width of characters that are not printable ASCII, in 1/1000 of the font size */
+const helveticaDefaultWidth = 556
+
+/* Returns the width of the text in Helvetica, for the given font size */
+func helveticaTextWidth(text string, fontSize float64) float64 {
+	width := 0
+	for _, c := range text {
+		if c >= ' ' && c <= '~' {
+			width += helveticaWidths[c-' ']
+		} else {
+			width += helveticaDefaultWidth
+		}
+	}
+	return float64(width) * fontSize / 1000.0
+}
@@ -0,0 +1,211 @@
+package draw
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/* Metadata of the tips of a tree, drawn as a heatmap beside the tips */
+type TipMetadata struct {
+	Columns []*MetadataColumn
+}
+
+/* Column of tip metadata */
+type MetadataColumn struct {
+	Name    string
+	Numeric bool              // All the values are numbers
+	Values  map[string]string // Values indexed by tip name. Missing values are absent
+}
+
+/* Entry of a legend: a colored box followed by a label */
+type LegendEntry struct {
+	Label string
+	Color color.Color
+}
+
+/*
+Colors of categorical values, used cyclically. They are chosen so that they
+are still different once converted to terminal colors.
+*/
+var categoricalPalette = []color.NRGBA{
+	{0xd6, 0x27, 0x28, 0xff}, {0x1f, 0x5f, 0xd6, 0xff}, {0x2c, 0xa0, 0x2c, 0xff},
+	{0xe6, 0xc3, 0x00, 0xff}, {0x9b, 0x30, 0xc8, 0xff}, {0x17, 0xbe, 0xcf, 0xff},
+	{0xe3, 0x77, 0xc2, 0xff}, {0x7f, 0x7f, 0x7f, 0xff},
+}
+
+/* Color scale of numeric values, from the minimum (blue) to the maximum (red) */
+var numericPalette = []color.NRGBA{
+	{0x2c, 0x3c, 0xc8, 0xff}, {0x17, 0xbe, 0xcf, 0xff}, {0x2c, 0xa0, 0x2c, 0xff},
+	{0xe6, 0xc3, 0x00, 0xff}, {0xd6, 0x27, 0x28, 0xff},
+}
+
+const (
+	heatmapCellWidth    = 0.05 // Width of heatmap columns, relative to the depth of the tree
+	numericLegendValues = 5    // Number of values in the legend of numeric columns
+)
+
+/*
+Reads tip metadata from a tab separated file. The first line is the header,
+and the first column contains the tip names. Empty values and "NA" are missing.
+Columns whose values are all numbers are numeric, the others are categorical.
+*/
+func ReadTipMetadata(r io.Reader) (*TipMetadata, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	var m *TipMetadata
+	tips := make(map[string]bool)
+	nline := 0
+	for scanner.Scan() {
+		nline++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if m == nil {
+			if len(fields) < 2 {
+				return nil, errors.New("Metadata header must have at least 2 columns")
+			}
+			m = &TipMetadata{make([]*MetadataColumn, len(fields)-1)}
+			for i, name := range fields[1:] {
+				m.Columns[i] = &MetadataColumn{name, true, make(map[string]string)}
+			}
+			continue
+		}
+		if len(fields) != len(m.Columns)+1 {
+			return nil, fmt.Errorf("Line %d of metadata has %d columns instead of %d", nline, len(fields), len(m.Columns)+1)
+		}
+		tip := fields[0]
+		if tips[tip] {
+			return nil, fmt.Errorf("Tip %s is present several times in metadata (line %d)", tip, nline)
+		}
+		tips[tip] = true
+		for i, v := range fields[1:] {
+			if v == "" || v == "NA" {
+				continue
+			}
+			c := m.Columns[i]
+			c.Values[tip] = v
+			if _, err := strconv.ParseFloat(v, 64); err != nil {
+				c.Numeric = false
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, errors.New("Metadata file is empty")
+	}
+	return m, nil
+}
+
+/*
+Returns the color of each value of the column, and the entries of its legend:
+categorical values in alphabetical order, or regularly spaced numeric values.
+*/
+func (c *MetadataColumn) colors() (map[string]color.Color, []LegendEntry) {
+	colors := make(map[string]color.Color)
+	entries := make([]LegendEntry, 0)
+	if c.Numeric {
+		min, max := math.Inf(1), math.Inf(-1)
+		for _, v := range c.Values {
+			f, _ := strconv.ParseFloat(v, 64)
+			min, max = math.Min(min, f), math.Max(max, f)
+		}
+		if len(c.Values) == 0 {
+			return colors, entries
+		}
+		for _, v := range c.Values {
+			f, _ := strconv.ParseFloat(v, 64)
+			colors[v] = numericColor(f, min, max)
+		}
+		if min == max {
+			return colors, []LegendEntry{{strconv.FormatFloat(min, 'g', 4, 64), numericColor(min, min, max)}}
+		}
+		for i := 0; i < numericLegendValues; i++ {
+			f := min + (max-min)*float64(i)/float64(numericLegendValues-1)
+			entries = append(entries, LegendEntry{strconv.FormatFloat(f, 'g', 4, 64), numericColor(f, min, max)})
+		}
+		return colors, entries
+	}
+	values := make([]string, 0)
+	for _, v := range c.Values {
+		if _, ok := colors[v]; !ok {
+			colors[v] = nil
+			values = append(values, v)
+		}
+	}
+	sort.Strings(values)
+	for i, v := range values {
+		colors[v] = categoricalPalette[i%len(categoricalPalette)]
+		entries = append(entries, LegendEntry{v, colors[v]})
+	}
+	return colors, entries
+}
+
+/* Color of the value v in the numeric scale going from min to max */
+func numericColor(v, min, max float64) color.NRGBA {
+	if max <= min {
+		return numericPalette[len(numericPalette)-1]
+	}
+	pos := (v - min) / (max - min) * float64(len(numericPalette)-1)
+	i := int(math.Floor(pos))
+	if i >= len(numericPalette)-1 {
+		return numericPalette[len(numericPalette)-1]
+	}
+	if i < 0 {
+		return numericPalette[0]
+	}
+	f := pos - float64(i)
+	c1, c2 := numericPalette[i], numericPalette[i+1]
+	mix := func(a, b uint8) uint8 { return uint8(math.Round(float64(a)*(1-f) + float64(b)*f)) }
+	return color.NRGBA{mix(c1.R, c2.R), mix(c1.G, c2.G), mix(c1.B, c2.B), 255}
+}
+
+/* Heatmap drawn on the right of the tips of a normal layout */
+type heatmap struct {
+	metadata  *TipMetadata
+	x         float64 // Tree coordinate of the left of the first column
+	cellWidth float64 // Width of the columns, in tree coordinates
+}
+
+/* Places the heatmap on the right of a tree of depth maxLength, after a one column gap */
+func (h *heatmap) place(maxLength float64) {
+	h.cellWidth = heatmapCellWidth * maxLength
+	if h.cellWidth <= 0 {
+		h.cellWidth = heatmapCellWidth
+	}
+	h.x = maxLength + h.cellWidth
+}
+
+/* Tree coordinate of the right of the last column */
+func (h *heatmap) end() float64 {
+	return h.x + float64(len(h.metadata.Columns))*h.cellWidth
+}
+
+/*
+Draws a cell for each tip having a value in each column, and a legend per
+column below the tree coordinate legendy. Tips are identified by their name.
+*/
+func (h *heatmap) draw(td TreeDrawer, tips []*layoutPoint, legendy float64) {
+	for i, c := range h.metadata.Columns {
+		colors, entries := c.colors()
+		x1 := h.x + float64(i)*h.cellWidth
+		x2 := x1 + h.cellWidth
+		for _, p := range tips {
+			if v, ok := c.Values[p.name]; ok {
+				style := Style{StrokeColor: colors[v], FillColor: colors[v]}
+				td.DrawPolygon([]float64{x1, x2, x2, x1}, []float64{p.y - 0.5, p.y - 0.5, p.y + 0.5, p.y + 0.5}, style)
+			}
+		}
+		td.DrawLegend(0, legendy, c.Name, entries)
+	}
+}
@@ -0,0 +1,136 @@
+package draw
+
+import (
+	"image/color"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadTipMetadata(t *testing.T) {
+	m, err := ReadTipMetadata(strings.NewReader("name\tcountry\tage\tdate\r\nA\tUK\t31\t2020-01-02\n\nB\tFR\tNA\t\nC\tUK\t4.5\t2020-03-01\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []*MetadataColumn{
+		{"country", false, map[string]string{"A": "UK", "B": "FR", "C": "UK"}},
+		{"age", true, map[string]string{"A": "31", "C": "4.5"}},
+		{"date", false, map[string]string{"A": "2020-01-02", "C": "2020-03-01"}},
+	}
+	if !reflect.DeepEqual(m.Columns, expected) {
+		for i, c := range m.Columns {
+			t.Errorf("Column %d: %+v, expected %+v", i, *c, *expected[i])
+		}
+	}
+
+	errors := []string{
+		"",
+		"name\n",
+		"name\tcountry\nA\tUK\tx\n",
+		"name\tcountry\nA\tUK\nA\tFR\n",
+	}
+	for _, tsv := range errors {
+		if _, err := ReadTipMetadata(strings.NewReader(tsv)); err == nil {
+			t.Errorf("ReadTipMetadata(%q) should return an error", tsv)
+		}
+	}
+}
+
+func TestMetadataColors(t *testing.T) {
+	categorical := &MetadataColumn{"country", false, map[string]string{"A": "UK", "B": "FR", "C": "UK", "D": "DE"}}
+	colors, entries := categorical.colors()
+	if exp := []LegendEntry{{"DE", categoricalPalette[0]}, {"FR", categoricalPalette[1]}, {"UK", categoricalPalette[2]}}; !reflect.DeepEqual(entries, exp) {
+		t.Errorf("Categorical legend %v, expected %v", entries, exp)
+	}
+	if colors["UK"] != categoricalPalette[2] {
+		t.Errorf("Color of UK: %v", colors["UK"])
+	}
+
+	numeric := &MetadataColumn{"age", true, map[string]string{"A": "0", "B": "10", "C": "5"}}
+	colors, entries = numeric.colors()
+	labels := make([]string, len(entries))
+	for i, e := range entries {
+		labels[i] = e.Label
+	}
+	if exp := []string{"0", "2.5", "5", "7.5", "10"}; !reflect.DeepEqual(labels, exp) {
+		t.Errorf("Numeric legend %q, expected %q", labels, exp)
+	}
+	if colors["0"] != numericPalette[0] || colors["5"] != numericPalette[2] || colors["10"] != numericPalette[4] {
+		t.Errorf("Numeric colors: %v", colors)
+	}
+
+	constant := &MetadataColumn{"age", true, map[string]string{"A": "3", "B": "3"}}
+	if _, entries := constant.colors(); len(entries) != 1 || entries[0].Label != "3" {
+		t.Errorf("Legend of a constant column: %v", entries)
+	}
+}
+
+func TestNumericColor(t *testing.T) {
+	tests := []struct {
+		v        float64
+		expected color.NRGBA
+	}{
+		{0, numericPalette[0]},
+		{-1, numericPalette[0]},
+		{100, numericPalette[4]},
+		{200, numericPalette[4]},
+		{50, numericPalette[2]},
+		// Halfway between the first two colors
+		{12.5, color.NRGBA{0x22, 0x7d, 0xcc, 0xff}},
+	}
+	for _, test := range tests {
+		if got := numericColor(test.v, 0, 100); got != test.expected {
+			t.Errorf("numericColor(%v) = %v, expected %v", test.v, got, test.expected)
+		}
+	}
+}
+
+func TestHeatmapLayout(t *testing.T) {
+	m, err := ReadTipMetadata(strings.NewReader("name\tcountry\tage\nA\tUK\t1\nB\tFR\t2\nX\tUK\t3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &recorder{}
+	layout := NewNormalLayout(r, true, true, false, false)
+	layout.SetHeatmap(m)
+	if err := layout.DrawTree(parse(t, "((A:1,B:1):1,C:2);")); err != nil {
+		t.Fatal(err)
+	}
+	// 2 columns of 0.1 after a gap of 0.1
+	if !near(r.maxWidth, 2.3) {
+		t.Errorf("Width with the heatmap %v, expected 2.3", r.maxWidth)
+	}
+	// Cells of A and B in both columns, C has no metadata
+	cells := r.ops("polygon")
+	if len(cells) != 4 {
+		t.Fatalf("%d heatmap cells, expected 4", len(cells))
+	}
+	for _, c := range cells {
+		if c.coords[0] < 2.1-1e-9 || c.coords[2] > 2.3+1e-9 || !near(c.coords[3], c.coords[1]) || !near(c.coords[5]-c.coords[1], 1) {
+			t.Errorf("Heatmap cell %v", c.coords)
+		}
+	}
+	if cells[0].style.FillColor != categoricalPalette[1] && cells[0].style.FillColor != categoricalPalette[0] {
+		t.Errorf("Fill color of the first cell: %v", cells[0].style.FillColor)
+	}
+	legends := r.ops("legend")
+	if len(legends) != 2 || legends[0].text != "country:FR,UK" || legends[1].text != "age:1,1.5,2,2.5,3" {
+		t.Errorf("Legends %v", legends)
+	}
+	// Names are drawn on the right of the heatmap
+	for name, p := range r.names() {
+		if p.coords[0] < 2.3-1e-9 {
+			t.Errorf("Name %s drawn at x=%v, over the heatmap", name, p.coords[0])
+		}
+	}
+
+	// Removing the heatmap
+	r = &recorder{}
+	layout = NewNormalLayout(r, true, true, false, false)
+	layout.SetHeatmap(m)
+	layout.SetHeatmap(nil)
+	layout.DrawTree(parse(t, "((A:1,B:1):1,C:2);"))
+	if len(r.ops("polygon")) != 0 || len(r.ops("legend")) != 0 || r.maxWidth != 2 {
+		t.Errorf("Heatmap drawn after SetHeatmap(nil)")
+	}
+}
@@ -1,6 +1,8 @@
 package draw
 
 import (
+	"time"
+
 	"github.com/benjamincjackson/gotree/tree"
 )
 
@@ -13,6 +15,11 @@ type normalLayout struct {
 	hasNodeComments        bool
 	hasSupport             bool
 	supportCutoff          float64
+	styler                 Styler
+	axes                   axisOptions
+	collapse               CladeCollapse
+	collapsed              map[*tree.Node]int // Collapsed nodes, with their number of tips
+	heatmap                *heatmap
 	cache                  *layoutCache
 }
 
@@ -26,6 +33,11 @@ func NewNormalLayout(td TreeDrawer, withBranchLengths, withTipLabels, withIntern
 		false,
 		withSupportCircles,
 		0.7,
+		nil,
+		axisOptions{},
+		CladeCollapse{},
+		nil,
+		nil,
 		newLayoutCache(),
 	}
 }
@@ -42,18 +54,67 @@ func (layout *normalLayout) SetDisplayNodeComments(s bool) {
 	layout.hasNodeComments = s
 }
 
+func (layout *normalLayout) SetStyler(s Styler) {
+	layout.styler = s
+}
+
+func (layout *normalLayout) SetScaleBar(show bool, unit string) {
+	layout.axes.scaleBar = show
+	layout.axes.scaleBarUnit = unit
+}
+
+/*
+Draws a calendar axis below the tree, going from the root to the most recent
+sample (the farthest tip), whose date is mostRecent. Branch lengths must be in time
+units, unitsPerYear being their number in one year (e.g. 1 for years, 365.25 for days).
+A zero date disables the axis.
+*/
+func (layout *normalLayout) SetTimeAxis(mostRecent time.Time, unitsPerYear float64) {
+	layout.axes.mostRecent = mostRecent
+	layout.axes.unitsPerYear = unitsPerYear
+}
+
+/*
+Collapses clades into triangles, labeled with the name of their root and their number of tips.
+*/
+func (layout *normalLayout) SetCollapse(c CladeCollapse) {
+	layout.collapse = c
+}
+
+/*
+Draws the metadata as a heatmap on the right of the tree, one column per metadata
+column, with a legend per column below the tree. Tip names are drawn on the right
+of the heatmap. A nil metadata removes the heatmap.
+*/
+func (layout *normalLayout) SetHeatmap(m *TipMetadata) {
+	layout.heatmap = nil
+	if m != nil && len(m.Columns) > 0 {
+		layout.heatmap = &heatmap{m, 0.0, 0.0}
+	}
+}
+
 /*
 Draw the tree on the specific drawer. Does not close the file. The caller must do it.
 */
 func (layout *normalLayout) DrawTree(t *tree.Tree) error {
 	var err error = nil
 	root := t.Root()
-	ntips := len(t.Tips())
+	layout.collapsed = layout.collapse.collapsedNodes(t)
+	ntips := nbDisplayedTips(t, layout.collapsed)
 	curNbTips := 0
-	maxLength, maxName := maxLength(t, layout.hasBranchLengths, layout.hasTipLabels, layout.hasNodeComments)
-	layout.drawer.SetMaxValues(maxLength, float64(ntips), maxName, 0)
-	layout.drawTreeRecur(root, nil, tree.NIL_SUPPORT, 0, 0, &curNbTips)
+	maxLength, maxName := maxLength(t, layout.hasBranchLengths, layout.hasTipLabels, layout.hasNodeComments, layout.collapsed)
+	width := maxLength
+	if layout.heatmap != nil {
+		layout.heatmap.place(maxLength)
+		width = layout.heatmap.end()
+	}
+	layout.drawer.SetMaxValues(width, float64(ntips), maxName, 0)
+	layout.drawTreeRecur(root, nil, tree.NIL_SUPPORT, 0, 0, &curNbTips, nodeStyle(layout.styler, root, nil, Style{}))
 	layout.drawTree()
+	layout.axes.draw(layout.drawer, float64(ntips-1), maxLength, layout.hasBranchLengths)
+	if layout.heatmap != nil {
+		layout.heatmap.draw(layout.drawer, layout.cache.tipLabelPoints, float64(ntips-1))
+	}
 	layout.drawer.Write()
 	return err
 }
@@ -61,16 +122,35 @@ func (layout *normalLayout) DrawTree(t *tree.Tree) error {
 /*
 Recursive function that draws the tree. Returns the yposition of the current node
 */
-func (layout *normalLayout) drawTreeRecur(n *tree.Node, prev *tree.Node, support, prevDistToRoot, distToRoot float64, curtip *int) float64 {
+func (layout *normalLayout) drawTreeRecur(n *tree.Node, prev *tree.Node, support, prevDistToRoot, distToRoot float64, curtip *int, style Style) float64 {
 	ypos := 0.0
 	nbchild := 0.0
-	if n.Tip() {
+	if ntips, ok := layout.collapsed[n]; ok {
 		ypos = float64(*curtip)
-		nbchild = 1.0
+		depth := cladeDepth(n, prev, layout.hasBranchLengths)
+		h := collapsedCladeHeight / 2.0
+		polygon := &layoutPolygon{
+			[]float64{distToRoot, distToRoot + depth, distToRoot + depth},
+			[]float64{ypos, ypos - h, ypos + h},
+			style,
+		}
+		layout.cache.polygons = append(layout.cache.polygons, polygon)
 		if layout.hasTipLabels {
-			node := &layoutPoint{distToRoot, ypos, 0.0, n.Name(), n.CommentsString()}
+			label := &layoutPoint{distToRoot + depth, ypos, 0.0, cladeLabel(n, ntips), "", style}
+			layout.cache.tipLabelPoints = append(layout.cache.tipLabelPoints, label)
+		}
+		*curtip++
+	} else if n.Tip() {
+		ypos = float64(*curtip)
+		nbchild = 1.0
+		node := &layoutPoint{distToRoot, ypos, 0.0, n.Name(), n.CommentsString(), style}
+		// Tip points are also needed to draw the heatmap
+		if layout.hasTipLabels || layout.heatmap != nil {
 			layout.cache.tipLabelPoints = append(layout.cache.tipLabelPoints, node)
 		}
+		if style.TipSymbol != NoSymbol {
+			layout.cache.symbolPoints = append(layout.cache.symbolPoints, node)
+		}
 		*curtip++
 	} else {
 		minpos := -1.0
@@ -82,7 +162,8 @@ func (layout *normalLayout) drawTreeRecur(n *tree.Node, prev *tree.Node, support
 				if !layout.hasBranchLengths || len == tree.NIL_LENGTH {
 					len = 1.0
 				}
-				temppos := layout.drawTreeRecur(child, n, supp, distToRoot, distToRoot+len, curtip)
+				cstyle := nodeStyle(layout.styler, child, n.Edges()[i], style)
+				temppos := layout.drawTreeRecur(child, n, supp, distToRoot, distToRoot+len, curtip, cstyle)
 				if minpos == -1 || minpos > temppos {
 					minpos = temppos
 				}
@@ -94,54 +175,64 @@ func (layout *normalLayout) drawTreeRecur(n *tree.Node, prev *tree.Node, support
 			}
 		}
 		ypos /= nbchild
-		line := &layoutVLine{distToRoot, minpos, maxpos, tree.NIL_SUPPORT}
+		line := &layoutVLine{distToRoot, minpos, maxpos, tree.NIL_SUPPORT, style}
 		layout.cache.verticalPaths = append(layout.cache.verticalPaths, line)
 
-		inode := &layoutPoint{distToRoot, ypos, 0.0, n.Name(), n.CommentsString()}
+		inode := &layoutPoint{distToRoot, ypos, 0.0, n.Name(), n.CommentsString(), style}
 		layout.cache.nodePoints = append(layout.cache.nodePoints, inode)
 	}
 
-	line := &layoutHLine{prevDistToRoot, distToRoot, ypos, support}
+	line := &layoutHLine{prevDistToRoot, distToRoot, ypos, support, style}
 	layout.cache.horizontalPaths = append(layout.cache.horizontalPaths, line)
 	return ypos
 }
 
 func (layout *normalLayout) drawTree() {
+	for _, p := range layout.cache.polygons {
+		layout.drawer.DrawPolygon(p.xs, p.ys, p.style)
+	}
 	for _, l := range layout.cache.horizontalPaths {
-		layout.drawer.DrawHLine(l.x1, l.x2, l.y)
+		layout.drawer.DrawHLine(l.x1, l.x2, l.y, l.style)
 	}
 	for _, l := range layout.cache.verticalPaths {
-		layout.drawer.DrawVLine(l.x, l.y1, l.y2)
+		layout.drawer.DrawVLine(l.x, l.y1, l.y2, l.style)
+	}
+	for _, p := range layout.cache.symbolPoints {
+		layout.drawer.DrawSymbol(p.x, p.y, p.style)
 	}
 	if layout.hasTipLabels {
 		for _, p := range layout.cache.tipLabelPoints {
+			x := p.x
+			if layout.heatmap != nil {
+				x = layout.heatmap.end()
+			}
 			if layout.hasNodeComments {
-				layout.drawer.DrawName(p.x, p.y, p.name+p.comment, 0.0)
+				layout.drawer.DrawName(x, p.y, p.name+p.comment, p.brAngle, p.style)
 			} else {
-				layout.drawer.DrawName(p.x, p.y, p.name, 0.0)
+				layout.drawer.DrawName(x, p.y, p.name, p.brAngle, p.style)
 			}
 		}
 	}
 	if layout.hasInternalNodeLabels {
 		for _, p := range layout.cache.nodePoints {
-			layout.drawer.DrawName(p.x, p.y, p.name, 0.0)
+			layout.drawer.DrawName(p.x, p.y, p.name, 0.0, p.style)
 		}
 	} else if layout.hasNodeComments {
 		for _, p := range layout.cache.nodePoints {
-			layout.drawer.DrawName(p.x, p.y, p.comment, 0.0)
+			layout.drawer.DrawName(p.x, p.y, p.comment, 0.0, p.style)
 		}
 	}
 
 	if layout.hasInternalNodeSymbols {
 		for _, p := range layout.cache.nodePoints {
-			layout.drawer.DrawCircle(p.x, p.y)
+			layout.drawer.DrawCircle(p.x, p.y, p.style)
 		}
 	}
 	for _, l := range layout.cache.horizontalPaths {
 		middlex := (l.x1 + l.x2) / 2.0
 		middley := (l.y + l.y) / 2.0
 		if layout.hasSupport && l.support != tree.NIL_SUPPORT && l.support >= layout.supportCutoff {
-			layout.drawer.DrawCircle(middlex, middley)
+			layout.drawer.DrawCircle(middlex, middley, l.style)
 		}
 	}
 }
@@ -0,0 +1,379 @@
+package draw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	imgdraw "image/draw"
+	"image/png"
+	"io"
+	"log"
+	"math"
+)
+
+/*
+PngTreeDrawer initializer. PngTreeDrawer draws trees as PNG images in any file,
+with anti-aliased lines and an embedded bitmap font (pure Go).
+
+Width, height and margins are given in points (1/72 inch): the size of the image
+in pixels is computed from the resolution dpi. The image is filled with the
+background color before drawing.
+*/
+func NewPngTreeDrawer(w io.Writer, width, height, leftmargin, rightmargin, topmargin, bottommargin int, dpi float64, background color.Color) TreeDrawer {
+	unit := dpi / 72.0
+	topx := func(v int) int { return int(math.Round(float64(v) * unit)) }
+	return &pngTreeDrawer{
+		w,
+		dpi,
+		background,
+		newFigure(topx(width), topx(height), topx(leftmargin), topx(rightmargin), topx(topmargin), topx(bottommargin), unit, bitmapTextWidth),
+		nil,
+	}
+}
+
+/*
+Draw a tree as PNG in any file.
+*/
+type pngTreeDrawer struct {
+	outwriter  io.Writer   // Output file
+	dpi        float64     // Resolution of the image
+	background color.Color // Background color
+	fig        *figure     // Primitives to draw
+	img        *image.RGBA // Image, created when written
+}
+
+func (pngd *pngTreeDrawer) SetMaxValues(maxLength, maxHeight float64, maxNameLength, maxNameHeight int) {
+	// The figure is scaled using the drawn objects when written
+}
+
+func (pngd *pngTreeDrawer) DrawHLine(x1, x2, y float64, style Style) {
+	pngd.fig.drawHLine(x1, x2, y, style)
+}
+
+func (pngd *pngTreeDrawer) DrawVLine(x, y1, y2 float64, style Style) {
+	pngd.fig.drawVLine(x, y1, y2, style)
+}
+
+func (pngd *pngTreeDrawer) DrawLine(x1, y1, x2, y2 float64, style Style) {
+	pngd.fig.drawLine(x1, y1, x2, y2, style)
+}
+
+func (pngd *pngTreeDrawer) DrawCurve(centerx, centery float64, middlex, middley float64, radius float64, startAngle, endAngle float64, style Style) {
+	pngd.fig.drawCurve(centerx, centery, radius, startAngle, endAngle, style)
+}
+
+func (pngd *pngTreeDrawer) DrawCircle(x, y float64, style Style) {
+	pngd.fig.drawCircle(x, y, style)
+}
+
+func (pngd *pngTreeDrawer) DrawPolygon(xs, ys []float64, style Style) {
+	pngd.fig.drawPolygon(xs, ys, style)
+}
+
+func (pngd *pngTreeDrawer) DrawSymbol(x, y float64, style Style) {
+	pngd.fig.drawSymbol(x, y, style)
+}
+
+func (pngd *pngTreeDrawer) DrawName(x, y float64, name string, angle float64, style Style) {
+	pngd.fig.drawName(x, y, name, angle, style)
+}
+
+func (pngd *pngTreeDrawer) DrawAxis(x1, x2, y float64, ticks []AxisTick, style Style) {
+	pngd.fig.drawAxis(x1, x2, y, ticks, style)
+}
+
+func (pngd *pngTreeDrawer) DrawTipLink(x1, y1, x2, y2 float64, name1, name2 string, style Style) {
+	pngd.fig.drawTipLink(x1, y1, x2, y2, name1, name2, style)
+}
+
+func (pngd *pngTreeDrawer) DrawLegend(x, y float64, title string, entries []LegendEntry) {
+	pngd.fig.drawLegend(x, y, title, entries)
+}
+
+func (pngd *pngTreeDrawer) Write() {
+	fig := pngd.fig
+	fig.fit()
+	pngd.img = image.NewRGBA(image.Rect(0, 0, fig.width, fig.height))
+	imgdraw.Draw(pngd.img, pngd.img.Bounds(), &image.Uniform{pngd.background}, image.Point{}, imgdraw.Src)
+
+	for _, p := range fig.polygons {
+		points := fig.polygonPixels(p)
+		pngd.fillPolygon(points, p.style.fillColor())
+		for i := range points {
+			j := (i + 1) % len(points)
+			pngd.drawSegment(points[i][0], points[i][1], points[j][0], points[j][1], p.style.strokeWidth()*fig.unit, p.style.strokeColor())
+		}
+	}
+	for _, l := range fig.lines {
+		pngd.drawSegment(fig.px(l.x1), fig.py(l.y1), fig.px(l.x2), fig.py(l.y2), l.style.strokeWidth()*fig.unit, l.style.strokeColor())
+	}
+	for _, c := range fig.curves {
+		// Curves are only drawn in figures having the same scale on both axes
+		r := c.radius * fig.xscale
+		nseg := int(math.Max(8, math.Abs(c.endAngle-c.startAngle)*r/2.0))
+		angles := arcAngles(c.startAngle, c.endAngle, nseg)
+		for i := 1; i < len(angles); i++ {
+			pngd.drawSegment(
+				fig.px(c.centerx)+r*math.Cos(angles[i-1]), fig.py(c.centery)+r*math.Sin(angles[i-1]),
+				fig.px(c.centerx)+r*math.Cos(angles[i]), fig.py(c.centery)+r*math.Sin(angles[i]),
+				c.style.strokeWidth()*fig.unit, c.style.strokeColor())
+		}
+	}
+	for _, l := range fig.links {
+		if x1, y1, x2, y2, ok := fig.linkPixels(l); ok {
+			pngd.drawSegment(x1, y1, x2, y2, l.style.strokeWidth()*fig.unit, l.style.strokeColor())
+		}
+	}
+	for _, c := range fig.circles {
+		pngd.drawDisc(fig.px(c.x), fig.py(c.y), circleRadius*fig.unit, c.style.strokeColor())
+	}
+	for _, s := range fig.symbols {
+		if poly := fig.symbolPolygon(s); poly != nil {
+			pngd.fillPolygon(poly, s.style.strokeColor())
+		} else {
+			pngd.drawDisc(fig.px(s.x), fig.py(s.y), symbolRadius*fig.unit, s.style.strokeColor())
+		}
+	}
+	for _, n := range fig.names {
+		pngd.drawText(n, n.style.labelColor())
+	}
+	for i, a := range fig.axes {
+		line, label := fig.axisOffsets(i)
+		y := fig.py(a.y) + line
+		pngd.drawSegment(fig.px(a.x1), y, fig.px(a.x2), y, a.style.strokeWidth()*fig.unit, a.style.strokeColor())
+		for _, t := range a.ticks {
+			if t.Mark {
+				pngd.drawSegment(fig.px(t.X), y, fig.px(t.X), y+tickLength*fig.unit, a.style.strokeWidth()*fig.unit, a.style.strokeColor())
+			}
+		}
+		for _, t := range fig.axisLabels(a) {
+			w := bitmapTextWidth(t.Label, axisFontSize*fig.unit)
+			pngd.rasterText(t.Label, axisFontSize*fig.unit, fig.px(t.X)-w/2.0, fig.py(a.y)+label, 1, 0, a.style.labelColor())
+		}
+	}
+	for i, l := range fig.legends {
+		x, y := fig.px(l.x), fig.py(l.y)+fig.legendOffset(i)
+		size := axisFontSize * fig.unit
+		boxes, labels, _ := fig.legendPositions(l)
+		pngd.rasterText(l.title, size, x, y, 1, 0, rgba(nil))
+		for j, e := range l.entries {
+			x1, y1 := x+boxes[j], y-size/2.0
+			pngd.fillPolygon([][2]float64{{x1, y1}, {x1 + size, y1}, {x1 + size, y1 + size}, {x1, y1 + size}}, rgba(e.Color))
+			pngd.rasterText(e.Label, size, x+labels[j], y, 1, 0, rgba(nil))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, pngd.img); err != nil {
+		log.Print("Cannot encode PNG image: " + err.Error())
+		return
+	}
+	if _, err := pngd.outwriter.Write(pngWithResolution(buf.Bytes(), pngd.dpi)); err != nil {
+		log.Print("Cannot write PNG image: " + err.Error())
+	}
+}
+
+func (pngd *pngTreeDrawer) Bounds() (width, height int) {
+	width, height = pngd.fig.width, pngd.fig.height
+	return
+}
+
+/* Blends the color c on the pixel (x,y) with the given coverage (between 0 and 1) */
+func (pngd *pngTreeDrawer) blend(x, y int, c color.NRGBA, coverage float64) {
+	if coverage <= 0 || !(image.Point{x, y}.In(pngd.img.Rect)) {
+		return
+	}
+	if coverage > 1 {
+		coverage = 1
+	}
+	a := coverage * float64(c.A) / 255.0
+	dst := pngd.img.RGBAAt(x, y)
+	mix := func(s, d uint8) uint8 {
+		return uint8(math.Round(float64(s)*a + float64(d)*(1-a)))
+	}
+	pngd.img.SetRGBA(x, y, color.RGBA{mix(c.R, dst.R), mix(c.G, dst.G), mix(c.B, dst.B), mix(255, dst.A)})
+}
+
+/*
+Draws an anti-aliased segment of the given width: the coverage of each pixel
+near the segment depends on the distance between its center and the segment.
+*/
+func (pngd *pngTreeDrawer) drawSegment(x1, y1, x2, y2, width float64, c color.NRGBA) {
+	hw := width / 2.0
+	dx, dy := x2-x1, y2-y1
+	coverage := func(px, py int) float64 {
+		return hw + 0.5 - distToSegment(float64(px)+0.5, float64(py)+0.5, x1, y1, x2, y2)
+	}
+	if math.Abs(dx) >= math.Abs(dy) {
+		if x1 > x2 {
+			x1, y1, x2, y2 = x2, y2, x1, y1
+		}
+		// Half thickness of the band of pixels to consider around the line
+		band := (hw + 1.0) * math.Hypot(dx, dy) / math.Max(math.Abs(dx), 1e-9)
+		for x := int(math.Floor(x1 - hw - 1)); x <= int(math.Ceil(x2+hw+1)); x++ {
+			yc := y1
+			if dx != 0 {
+				yc = y1 + (math.Max(x1, math.Min(x2, float64(x)+0.5))-x1)*(y2-y1)/(x2-x1)
+			}
+			for y := int(math.Floor(yc - band)); y <= int(math.Ceil(yc+band)); y++ {
+				pngd.blend(x, y, c, coverage(x, y))
+			}
+		}
+	} else {
+		if y1 > y2 {
+			x1, y1, x2, y2 = x2, y2, x1, y1
+		}
+		band := (hw + 1.0) * math.Hypot(dx, dy) / math.Abs(dy)
+		for y := int(math.Floor(y1 - hw - 1)); y <= int(math.Ceil(y2+hw+1)); y++ {
+			xc := x1 + (math.Max(y1, math.Min(y2, float64(y)+0.5))-y1)*(x2-x1)/(y2-y1)
+			for x := int(math.Floor(xc - band)); x <= int(math.Ceil(xc+band)); x++ {
+				pngd.blend(x, y, c, coverage(x, y))
+			}
+		}
+	}
+}
+
+/* Draws an anti-aliased filled disc */
+func (pngd *pngTreeDrawer) drawDisc(cx, cy, r float64, c color.NRGBA) {
+	for y := int(math.Floor(cy - r - 1)); y <= int(math.Ceil(cy+r+1)); y++ {
+		for x := int(math.Floor(cx - r - 1)); x <= int(math.Ceil(cx+r+1)); x++ {
+			d := math.Hypot(float64(x)+0.5-cx, float64(y)+0.5-cy)
+			pngd.blend(x, y, c, r+0.5-d)
+		}
+	}
+}
+
+/*
+Draws a label with the bitmap font, rotated along the angle of its incoming
+branch. Labels pointing to the left are flipped, so that they are not upside down.
+Each pixel of the image is sampled 4 times in the label coordinates, for anti-aliasing.
+*/
+func (pngd *pngTreeDrawer) drawText(n figureName, c color.NRGBA) {
+	fig := pngd.fig
+	w := bitmapTextWidth(n.name, fig.fontSize)
+	cos, sin := math.Cos(n.angle), math.Sin(n.angle)
+	pad := fig.namePadding(n)
+	// Text origin (left end, vertical center) and direction
+	ox, oy := fig.px(n.x)+pad*cos, fig.py(n.y)+pad*sin
+	dirx, diry := cos, sin
+	if cos < 0 {
+		ox, oy = ox+w*cos, oy+w*sin
+		dirx, diry = -cos, -sin
+	}
+	pngd.rasterText(n.name, fig.fontSize, ox, oy, dirx, diry, c)
+}
+
+/*
+Draws the text with the bitmap font, from its origin (left end, vertical center)
+along the direction (dirx,diry), which must be a unit vector.
+*/
+func (pngd *pngTreeDrawer) rasterText(text string, fontSize, ox, oy, dirx, diry float64, c color.NRGBA) {
+	scale := fontSize / bitmapCellHeight
+	runes := []rune(text)
+	w := float64(len(runes)*bitmapCellWidth) * scale
+	h := fontSize
+	// Bounding box of the text in the image
+	xmin, ymin, xmax, ymax := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, u := range []float64{0, w} {
+		for _, v := range []float64{-h / 2, h / 2} {
+			x, y := ox+u*dirx-v*diry, oy+u*diry+v*dirx
+			xmin, xmax = math.Min(xmin, x), math.Max(xmax, x)
+			ymin, ymax = math.Min(ymin, y), math.Max(ymax, y)
+		}
+	}
+	for y := int(math.Floor(ymin)); y <= int(math.Ceil(ymax)); y++ {
+		for x := int(math.Floor(xmin)); x <= int(math.Ceil(xmax)); x++ {
+			covered := 0
+			for _, sx := range []float64{0.25, 0.75} {
+				for _, sy := range []float64{0.25, 0.75} {
+					// Coordinates of the sample in the text (u along the text, v downwards)
+					px, py := float64(x)+sx-ox, float64(y)+sy-oy
+					u := px*dirx + py*diry
+					v := -px*diry + py*dirx + h/2
+					if u < 0 || v < 0 {
+						continue
+					}
+					cell := int(u / (bitmapCellWidth * scale))
+					if cell >= len(runes) {
+						continue
+					}
+					col := int((u - float64(cell)*bitmapCellWidth*scale) / scale)
+					row := int(v / scale)
+					if bitmapPixel(runes[cell], col, row) {
+						covered++
+					}
+				}
+			}
+			pngd.blend(x, y, c, float64(covered)/4.0)
+		}
+	}
+}
+
+/*
+Fills the polygon, with anti-aliasing: each pixel of its bounding box is
+sampled 16 times, and a sample is inside if it is inside the polygon (even-odd rule).
+*/
+func (pngd *pngTreeDrawer) fillPolygon(poly [][2]float64, c color.NRGBA) {
+	if len(poly) < 3 {
+		return
+	}
+	xmin, ymin, xmax, ymax := poly[0][0], poly[0][1], poly[0][0], poly[0][1]
+	for _, p := range poly {
+		xmin, xmax = math.Min(xmin, p[0]), math.Max(xmax, p[0])
+		ymin, ymax = math.Min(ymin, p[1]), math.Max(ymax, p[1])
+	}
+	// Clips the bounding box to the image
+	bounds := pngd.img.Rect
+	xmin, ymin = math.Max(xmin, float64(bounds.Min.X)), math.Max(ymin, float64(bounds.Min.Y))
+	xmax, ymax = math.Min(xmax, float64(bounds.Max.X)), math.Min(ymax, float64(bounds.Max.Y))
+	for y := int(math.Floor(ymin)); y < int(math.Ceil(ymax)); y++ {
+		for x := int(math.Floor(xmin)); x < int(math.Ceil(xmax)); x++ {
+			covered := 0
+			for i := 0; i < 4; i++ {
+				for j := 0; j < 4; j++ {
+					if insidePolygon(poly, float64(x)+(float64(i)+0.5)/4.0, float64(y)+(float64(j)+0.5)/4.0) {
+						covered++
+					}
+				}
+			}
+			pngd.blend(x, y, c, float64(covered)/16.0)
+		}
+	}
+}
+
+/* Returns the distance between point (px,py) and the segment (x1,y1)-(x2,y2) */
+func distToSegment(px, py, x1, y1, x2, y2 float64) float64 {
+	dx, dy := x2-x1, y2-y1
+	l2 := dx*dx + dy*dy
+	t := 0.0
+	if l2 > 0 {
+		t = math.Max(0, math.Min(1, ((px-x1)*dx+(py-y1)*dy)/l2))
+	}
+	return math.Hypot(px-(x1+t*dx), py-(y1+t*dy))
+}
+
+/*
+Inserts a pHYs chunk, giving the resolution of the image, after
+the IHDR chunk of the encoded PNG image
+*/
+func pngWithResolution(data []byte, dpi float64) []byte {
+	// Signature (8 bytes) + IHDR chunk (4 length + 4 type + 13 data + 4 crc)
+	const ihdrEnd = 8 + 25
+	if len(data) < ihdrEnd || dpi <= 0 {
+		return data
+	}
+	ppm := uint32(math.Round(dpi / 0.0254))
+	chunk := make([]byte, 4+4+9+4)
+	binary.BigEndian.PutUint32(chunk[0:], 9)
+	copy(chunk[4:], "pHYs")
+	binary.BigEndian.PutUint32(chunk[8:], ppm)
+	binary.BigEndian.PutUint32(chunk[12:], ppm)
+	chunk[16] = 1 // unit is the meter
+	binary.BigEndian.PutUint32(chunk[17:], crc32.ChecksumIEEE(chunk[4:17]))
+	out := make([]byte, 0, len(data)+len(chunk))
+	out = append(out, data[:ihdrEnd]...)
+	out = append(out, chunk...)
+	return append(out, data[ihdrEnd:]...)
+}
@@ -0,0 +1,66 @@
+package draw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestPngTreeDrawer(t *testing.T) {
+	background := color.RGBA{255, 255, 200, 255}
+	tests := []struct {
+		dpi           float64
+		width, height int // Expected size in pixels
+	}{
+		{72, 200, 100},
+		{144, 400, 200},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		d := NewPngTreeDrawer(&buf, 200, 100, 10, 10, 10, 10, test.dpi, background)
+		if err := NewNormalLayout(d, true, true, false, false).DrawTree(parse(t, "((A:1,B:1)0.9:1,C:2);")); err != nil {
+			t.Fatal(err)
+		}
+		data := buf.Bytes()
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("dpi %v: invalid PNG: %v", test.dpi, err)
+		}
+		if b := img.Bounds(); b.Dx() != test.width || b.Dy() != test.height {
+			t.Errorf("dpi %v: image size %dx%d, expected %dx%d", test.dpi, b.Dx(), b.Dy(), test.width, test.height)
+		}
+		if w, h := d.Bounds(); w != test.width || h != test.height {
+			t.Errorf("dpi %v: Bounds() = %dx%d, expected %dx%d", test.dpi, w, h, test.width, test.height)
+		}
+
+		// The pHYs chunk follows the IHDR chunk and gives the resolution in pixels per meter
+		if string(data[37:41]) != "pHYs" {
+			t.Fatalf("dpi %v: no pHYs chunk after IHDR", test.dpi)
+		}
+		if ppm, exp := binary.BigEndian.Uint32(data[41:45]), uint32(test.dpi/0.0254+0.5); ppm != exp {
+			t.Errorf("dpi %v: %d pixels per meter, expected %d", test.dpi, ppm, exp)
+		}
+
+		// Something is drawn, inside the margins
+		margin := int(10 * test.dpi / 72)
+		drawn := 0
+		for x := 0; x < test.width; x++ {
+			for y := 0; y < test.height; y++ {
+				r, g, b, a := img.At(x, y).RGBA()
+				if r>>8 == 255 && g>>8 == 255 && b>>8 == 200 && a>>8 == 255 {
+					continue
+				}
+				drawn++
+				if x < margin-2 || x > test.width-margin+2 || y < margin-2 || y > test.height-margin+2 {
+					t.Errorf("dpi %v: pixel (%d,%d) drawn in the margins", test.dpi, x, y)
+					return
+				}
+			}
+		}
+		if drawn == 0 {
+			t.Errorf("dpi %v: nothing is drawn", test.dpi)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package draw
+
+import (
+	"image/color"
+	"strings"
+
+	"github.com/benjamincjackson/gotree/tree"
+)
+
+/* Symbol drawn at the tips of the tree */
+type Symbol int
+
+const (
+	NoSymbol Symbol = iota
+	CircleSymbol
+	SquareSymbol
+	TriangleSymbol
+)
+
+/*
+Style of a branch and of the node below it. The zero value is the
+default style: black branches of width 1, black labels, no tip symbol
+and light gray collapsed clades.
+*/
+type Style struct {
+	StrokeColor color.Color // Color of the branch, and of the symbols. Black if nil
+	StrokeWidth float64     // Width of the branch, in points. 1 if 0
+	LabelColor  color.Color // Color of the node label. Black if nil
+	TipSymbol   Symbol      // Symbol drawn at the tip (ignored for internal nodes)
+	FillColor   color.Color // Fill color of collapsed clades. Light gray if nil
+}
+
+/*
+Function giving the style of a node and of its incoming branch e (nil for
+the root). parent is the style given to the parent node (the default style
+for the root), so that clades can inherit the style of their ancestors.
+*/
+type Styler func(n *tree.Node, e *tree.Edge, parent Style) Style
+
+/*
+Rule of a rule based styler: nodes having a comment key=value, or whose
+incoming branch has such comment, are given the style.
+*/
+type StyleRule struct {
+	Key   string
+	Value string
+	Style Style
+}
+
+/*
+Returns a styler that applies the first rule matching the comments of each node
+and of its incoming branch. Comments may be FigTree/BEAST like: [&country=UK,lineage=B.1].
+
+If inherit is true, nodes that do not match any rule are given the style of their parent,
+so that a rule matching an internal node colors the whole clade. Otherwise they are
+given the default style.
+*/
+func NewRuleStyler(rules []StyleRule, inherit bool) Styler {
+	return func(n *tree.Node, e *tree.Edge, parent Style) Style {
+		for _, r := range rules {
+			if hasComment(n.GetComments(), r.Key, r.Value) || (e != nil && hasComment(e.GetComments(), r.Key, r.Value)) {
+				return r.Style
+			}
+		}
+		if inherit {
+			return parent
+		}
+		return Style{}
+	}
+}
+
+/*
+Returns true if one of the comments contains the pair key=value. For annotation
+comments ([&key={a,b}]), value may also be one of the elements of a list.
+*/
+func hasComment(comments []string, key, value string) bool {
+	for _, c := range comments {
+		if annots, ok := tree.ParseAnnotations(c); ok {
+			for _, a := range annots {
+				if a.Key == key && hasValue(a.Value, value) {
+					return true
+				}
+			}
+			continue
+		}
+		for _, kv := range strings.Split(strings.TrimPrefix(c, "&"), ",") {
+			kv := strings.SplitN(kv, "=", 2)
+			if len(kv) == 2 && strings.TrimSpace(kv[0]) == key && strings.Trim(strings.TrimSpace(kv[1]), "\"'") == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/* Returns true if the annotation value is value, or is a list containing it */
+func hasValue(v tree.AnnotationValue, value string) bool {
+	switch v.Type {
+	case tree.ListAnnotation, tree.RangeAnnotation:
+		for _, e := range v.List {
+			if hasValue(e, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return v.Text == value
+}
+
+/* Computes the style of the node, using the styler if any */
+func nodeStyle(styler Styler, n *tree.Node, e *tree.Edge, parent Style) Style {
+	if styler == nil {
+		return Style{}
+	}
+	return styler(n, e, parent)
+}
+
+func (s Style) strokeColor() color.NRGBA {
+	return rgba(s.StrokeColor)
+}
+
+func (s Style) labelColor() color.NRGBA {
+	return rgba(s.LabelColor)
+}
+
+func (s Style) fillColor() color.NRGBA {
+	if s.FillColor == nil {
+		return color.NRGBA{211, 211, 211, 255}
+	}
+	return rgba(s.FillColor)
+}
+
+func (s Style) strokeWidth() float64 {
+	if s.StrokeWidth <= 0 {
+		return 1.0
+	}
+	return s.StrokeWidth
+}
+
+/* Converts the color to non premultiplied RGBA, black if nil */
+func rgba(c color.Color) color.NRGBA {
+	if c == nil {
+		return color.NRGBA{0, 0, 0, 255}
+	}
+	return color.NRGBAModel.Convert(c).(color.NRGBA)
+}
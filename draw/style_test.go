@@ -0,0 +1,82 @@
+package draw
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestHasComment(t *testing.T) {
+	tests := []struct {
+		comments []string
+		key      string
+		value    string
+		expected bool
+	}{
+		{[]string{"country=UK"}, "country", "UK", true},
+		{[]string{"country=UK"}, "country", "FR", false},
+		{[]string{"&country=UK,lineage=B.1"}, "lineage", "B.1", true},
+		{[]string{"&country=\"UK\""}, "country", "UK", true},
+		{[]string{"&country={UK,FR}"}, "country", "FR", true},
+		{[]string{"&country={UK,FR}"}, "country", "{UK,FR}", false},
+		{[]string{"&height_range={1.5,2}"}, "height_range", "2", true},
+		{[]string{"x", "&lineage=A"}, "lineage", "A", true},
+		{[]string{"lineage"}, "lineage", "", false},
+		{nil, "country", "UK", false},
+	}
+	for _, test := range tests {
+		if got := hasComment(test.comments, test.key, test.value); got != test.expected {
+			t.Errorf("hasComment(%q, %s, %s) = %v, expected %v", test.comments, test.key, test.value, got, test.expected)
+		}
+	}
+}
+
+func TestRuleStyler(t *testing.T) {
+	red := Style{StrokeColor: color.NRGBA{255, 0, 0, 255}, TipSymbol: CircleSymbol}
+	blue := Style{LabelColor: color.NRGBA{0, 0, 255, 255}, StrokeWidth: 2}
+	rules := []StyleRule{{"lineage", "B", red}, {"country", "UK", blue}}
+	nw := "((A[&country=UK],B)[&lineage=B],C[&country=UK],D);"
+	tests := []struct {
+		inherit  bool
+		expected map[string]Style
+	}{
+		// A matches the blue rule, B inherits red from its parent
+		{true, map[string]Style{"A": blue, "B": red, "C": blue, "D": {}}},
+		{false, map[string]Style{"A": blue, "B": {}, "C": blue, "D": {}}},
+	}
+	for _, test := range tests {
+		r := &recorder{}
+		layout := NewNormalLayout(r, false, true, false, false)
+		layout.SetStyler(NewRuleStyler(rules, test.inherit))
+		if err := layout.DrawTree(parse(t, nw)); err != nil {
+			t.Fatal(err)
+		}
+		names := r.names()
+		for name, style := range test.expected {
+			if got := names[name].style; got != style {
+				t.Errorf("inherit=%v: style of %s = %+v, expected %+v", test.inherit, name, got, style)
+			}
+		}
+		// Only the red tip has a symbol
+		symbols := r.ops("symbol")
+		if nsym := map[bool]int{true: 1, false: 0}[test.inherit]; len(symbols) != nsym {
+			t.Errorf("inherit=%v: %d symbols drawn, expected %d", test.inherit, len(symbols), nsym)
+		}
+	}
+}
+
+func TestStyleDefaults(t *testing.T) {
+	var s Style
+	if c := s.strokeColor(); c != (color.NRGBA{0, 0, 0, 255}) {
+		t.Errorf("Default stroke color = %v", c)
+	}
+	if w := s.strokeWidth(); w != 1 {
+		t.Errorf("Default stroke width = %v", w)
+	}
+	if c := s.fillColor(); c != (color.NRGBA{211, 211, 211, 255}) {
+		t.Errorf("Default fill color = %v", c)
+	}
+	s = Style{StrokeColor: color.RGBA{0, 0, 128, 128}}
+	if c := s.strokeColor(); c != (color.NRGBA{0, 0, 255, 128}) {
+		t.Errorf("Non premultiplied stroke color = %v", c)
+	}
+}
@@ -0,0 +1,258 @@
+package draw
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strings"
+)
+
+/*
+SvgTreeDrawer initializer. SvgTreeDrawer draws trees as SVG images of
+the given size (in pixels) in any file. The tree is drawn inside the margins,
+and scaled so that labels fit in the image, using Helvetica font metrics.
+*/
+func NewSvgTreeDrawer(w io.Writer, width, height, leftmargin, rightmargin, topmargin, bottommargin int) TreeDrawer {
+	return &svgTreeDrawer{
+		w,
+		newFigure(width, height, leftmargin, rightmargin, topmargin, bottommargin, 1.0, helveticaTextWidth),
+	}
+}
+
+/*
+Draw a tree as SVG in any file.
+*/
+type svgTreeDrawer struct {
+	outwriter io.Writer // Output file
+	fig       *figure   // Primitives to draw
+}
+
+func (svgd *svgTreeDrawer) SetMaxValues(maxLength, maxHeight float64, maxNameLength, maxNameHeight int) {
+	// The figure is scaled using the drawn objects when written
+}
+
+func (svgd *svgTreeDrawer) DrawHLine(x1, x2, y float64, style Style) {
+	svgd.fig.drawHLine(x1, x2, y, style)
+}
+
+func (svgd *svgTreeDrawer) DrawVLine(x, y1, y2 float64, style Style) {
+	svgd.fig.drawVLine(x, y1, y2, style)
+}
+
+func (svgd *svgTreeDrawer) DrawLine(x1, y1, x2, y2 float64, style Style) {
+	svgd.fig.drawLine(x1, y1, x2, y2, style)
+}
+
+func (svgd *svgTreeDrawer) DrawCurve(centerx, centery float64, middlex, middley float64, radius float64, startAngle, endAngle float64, style Style) {
+	svgd.fig.drawCurve(centerx, centery, radius, startAngle, endAngle, style)
+}
+
+func (svgd *svgTreeDrawer) DrawCircle(x, y float64, style Style) {
+	svgd.fig.drawCircle(x, y, style)
+}
+
+func (svgd *svgTreeDrawer) DrawPolygon(xs, ys []float64, style Style) {
+	svgd.fig.drawPolygon(xs, ys, style)
+}
+
+func (svgd *svgTreeDrawer) DrawSymbol(x, y float64, style Style) {
+	svgd.fig.drawSymbol(x, y, style)
+}
+
+func (svgd *svgTreeDrawer) DrawName(x, y float64, name string, angle float64, style Style) {
+	svgd.fig.drawName(x, y, name, angle, style)
+}
+
+func (svgd *svgTreeDrawer) DrawAxis(x1, x2, y float64, ticks []AxisTick, style Style) {
+	svgd.fig.drawAxis(x1, x2, y, ticks, style)
+}
+
+func (svgd *svgTreeDrawer) DrawTipLink(x1, y1, x2, y2 float64, name1, name2 string, style Style) {
+	svgd.fig.drawTipLink(x1, y1, x2, y2, name1, name2, style)
+}
+
+func (svgd *svgTreeDrawer) DrawLegend(x, y float64, title string, entries []LegendEntry) {
+	svgd.fig.drawLegend(x, y, title, entries)
+}
+
+func (svgd *svgTreeDrawer) Write() {
+	fig := svgd.fig
+	fig.fit()
+	b := bufio.NewWriter(svgd.outwriter)
+	fmt.Fprintf(b, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(b, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n", fig.width, fig.height, fig.width, fig.height)
+	fmt.Fprintf(b, "<rect width=\"100%%\" height=\"100%%\" fill=\"white\"/>\n")
+
+	fmt.Fprintf(b, "<g stroke=\"black\" stroke-width=\"1\" stroke-linejoin=\"round\">\n")
+	for _, p := range fig.polygons {
+		points := make([]string, len(p.xs))
+		for i, v := range fig.polygonPixels(p) {
+			points[i] = svgNum(v[0]) + "," + svgNum(v[1])
+		}
+		fmt.Fprintf(b, "<polygon points=\"%s\"%s%s/>\n", strings.Join(points, " "), svgFill(p.style.fillColor()), svgStroke(p.style))
+	}
+	fmt.Fprintf(b, "</g>\n")
+
+	fmt.Fprintf(b, "<g stroke=\"black\" stroke-width=\"1\" stroke-linecap=\"square\" fill=\"none\">\n")
+	for _, l := range fig.lines {
+		fmt.Fprintf(b, "<line x1=\"%s\" y1=\"%s\" x2=\"%s\" y2=\"%s\"%s/>\n", svgNum(fig.px(l.x1)), svgNum(fig.py(l.y1)), svgNum(fig.px(l.x2)), svgNum(fig.py(l.y2)), svgStroke(l.style))
+	}
+	for _, c := range fig.curves {
+		// Curves are only drawn in figures having the same scale on both axes
+		r := c.radius * fig.xscale
+		x1 := fig.px(c.centerx) + r*math.Cos(c.startAngle)
+		y1 := fig.py(c.centery) + r*math.Sin(c.startAngle)
+		x2 := fig.px(c.centerx) + r*math.Cos(c.endAngle)
+		y2 := fig.py(c.centery) + r*math.Sin(c.endAngle)
+		largeArc := 0
+		if c.endAngle-c.startAngle > math.Pi {
+			largeArc = 1
+		}
+		fmt.Fprintf(b, "<path d=\"M %s %s A %s %s 0 %d 1 %s %s\"%s/>\n", svgNum(x1), svgNum(y1), svgNum(r), svgNum(r), largeArc, svgNum(x2), svgNum(y2), svgStroke(c.style))
+	}
+	for _, l := range fig.links {
+		if x1, y1, x2, y2, ok := fig.linkPixels(l); ok {
+			fmt.Fprintf(b, "<line x1=\"%s\" y1=\"%s\" x2=\"%s\" y2=\"%s\"%s/>\n", svgNum(x1), svgNum(y1), svgNum(x2), svgNum(y2), svgStroke(l.style))
+		}
+	}
+	fmt.Fprintf(b, "</g>\n")
+
+	fmt.Fprintf(b, "<g fill=\"black\">\n")
+	for _, c := range fig.circles {
+		fmt.Fprintf(b, "<circle cx=\"%s\" cy=\"%s\" r=\"%s\"%s/>\n", svgNum(fig.px(c.x)), svgNum(fig.py(c.y)), svgNum(circleRadius), svgFill(c.style.StrokeColor))
+	}
+	for _, s := range fig.symbols {
+		if poly := fig.symbolPolygon(s); poly != nil {
+			points := make([]string, len(poly))
+			for i, p := range poly {
+				points[i] = svgNum(p[0]) + "," + svgNum(p[1])
+			}
+			fmt.Fprintf(b, "<polygon points=\"%s\"%s/>\n", strings.Join(points, " "), svgFill(s.style.StrokeColor))
+		} else {
+			fmt.Fprintf(b, "<circle cx=\"%s\" cy=\"%s\" r=\"%s\"%s/>\n", svgNum(fig.px(s.x)), svgNum(fig.py(s.y)), svgNum(symbolRadius), svgFill(s.style.StrokeColor))
+		}
+	}
+	fmt.Fprintf(b, "</g>\n")
+
+	fmt.Fprintf(b, "<g font-family=\"Helvetica, Arial, sans-serif\" font-size=\"%s\" fill=\"black\">\n", svgNum(fig.fontSize))
+	for _, n := range fig.names {
+		svgd.writeName(b, n)
+	}
+	fmt.Fprintf(b, "</g>\n")
+
+	for i, a := range fig.axes {
+		svgd.writeAxis(b, i, a)
+	}
+	for i, l := range fig.legends {
+		svgd.writeLegend(b, i, l)
+	}
+	fmt.Fprintf(b, "</svg>\n")
+	_ = b.Flush()
+}
+
+// Writes a label, rotated along the angle of its incoming branch. Labels
+// pointing to the left are flipped, so that they are not upside down.
+func (svgd *svgTreeDrawer) writeName(b *bufio.Writer, n figureName) {
+	fig := svgd.fig
+	x := fig.px(n.x) + fig.namePadding(n)*math.Cos(n.angle)
+	y := fig.py(n.y) + fig.namePadding(n)*math.Sin(n.angle)
+	anchor := "start"
+	degrees := n.angle * 180.0 / math.Pi
+	if math.Cos(n.angle) < 0 {
+		anchor = "end"
+		degrees += 180.0
+	}
+	fmt.Fprintf(b, "<text x=\"%s\" y=\"%s\" dominant-baseline=\"central\" text-anchor=\"%s\"%s", svgNum(x), svgNum(y), anchor, svgFill(n.style.LabelColor))
+	degrees = math.Mod(degrees, 360.0)
+	if degrees != 0 {
+		fmt.Fprintf(b, " transform=\"rotate(%s %s %s)\"", svgNum(degrees), svgNum(x), svgNum(y))
+	}
+	b.WriteString(">")
+	_ = xml.EscapeText(b, []byte(n.name))
+	b.WriteString("</text>\n")
+}
+
+// Writes the i-th axis: its line, its tick marks and its labels
+func (svgd *svgTreeDrawer) writeAxis(b *bufio.Writer, i int, a figureAxis) {
+	fig := svgd.fig
+	line, label := fig.axisOffsets(i)
+	y := fig.py(a.y) + line
+	fmt.Fprintf(b, "<g stroke=\"black\" stroke-width=\"1\" fill=\"none\">\n")
+	fmt.Fprintf(b, "<line x1=\"%s\" y1=\"%s\" x2=\"%s\" y2=\"%s\"%s/>\n", svgNum(fig.px(a.x1)), svgNum(y), svgNum(fig.px(a.x2)), svgNum(y), svgStroke(a.style))
+	for _, t := range a.ticks {
+		if t.Mark {
+			fmt.Fprintf(b, "<line x1=\"%s\" y1=\"%s\" x2=\"%s\" y2=\"%s\"%s/>\n", svgNum(fig.px(t.X)), svgNum(y), svgNum(fig.px(t.X)), svgNum(y+tickLength), svgStroke(a.style))
+		}
+	}
+	fmt.Fprintf(b, "</g>\n")
+	fmt.Fprintf(b, "<g font-family=\"Helvetica, Arial, sans-serif\" font-size=\"%s\" fill=\"black\" text-anchor=\"middle\">\n", svgNum(axisFontSize))
+	for _, t := range fig.axisLabels(a) {
+		fmt.Fprintf(b, "<text x=\"%s\" y=\"%s\" dominant-baseline=\"central\"%s>", svgNum(fig.px(t.X)), svgNum(fig.py(a.y)+label), svgFill(a.style.LabelColor))
+		_ = xml.EscapeText(b, []byte(t.Label))
+		b.WriteString("</text>\n")
+	}
+	fmt.Fprintf(b, "</g>\n")
+}
+
+// Writes the i-th legend: its title, and a colored box and a label per entry
+func (svgd *svgTreeDrawer) writeLegend(b *bufio.Writer, i int, l figureLegend) {
+	fig := svgd.fig
+	x, y := fig.px(l.x), fig.py(l.y)+fig.legendOffset(i)
+	boxes, labels, _ := fig.legendPositions(l)
+	fmt.Fprintf(b, "<g font-family=\"Helvetica, Arial, sans-serif\" font-size=\"%s\" fill=\"black\">\n", svgNum(axisFontSize))
+	fmt.Fprintf(b, "<text x=\"%s\" y=\"%s\" dominant-baseline=\"central\">", svgNum(x), svgNum(y))
+	_ = xml.EscapeText(b, []byte(l.title))
+	b.WriteString("</text>\n")
+	for j, e := range l.entries {
+		fmt.Fprintf(b, "<rect x=\"%s\" y=\"%s\" width=\"%s\" height=\"%s\"%s/>\n", svgNum(x+boxes[j]), svgNum(y-axisFontSize/2.0), svgNum(axisFontSize), svgNum(axisFontSize), svgFill(e.Color))
+		fmt.Fprintf(b, "<text x=\"%s\" y=\"%s\" dominant-baseline=\"central\">", svgNum(x+labels[j]), svgNum(y))
+		_ = xml.EscapeText(b, []byte(e.Label))
+		b.WriteString("</text>\n")
+	}
+	fmt.Fprintf(b, "</g>\n")
+}
+
+func (svgd *svgTreeDrawer) Bounds() (width, height int) {
+	width, height = svgd.fig.width, svgd.fig.height
+	return
+}
+
+/* Stroke attributes of the style, if it is not the default one */
+func svgStroke(style Style) string {
+	attrs := ""
+	if style.StrokeColor != nil {
+		attrs += " stroke=\"" + svgColor(style.strokeColor()) + "\""
+		if a := style.strokeColor().A; a != 255 {
+			attrs += " stroke-opacity=\"" + svgNum(float64(a)/255.0) + "\""
+		}
+	}
+	if style.StrokeWidth > 0 {
+		attrs += " stroke-width=\"" + svgNum(style.StrokeWidth) + "\""
+	}
+	return attrs
+}
+
+/* Fill attributes of the color, if it is not the default one */
+func svgFill(c color.Color) string {
+	if c == nil {
+		return ""
+	}
+	n := rgba(c)
+	attrs := " fill=\"" + svgColor(n) + "\""
+	if n.A != 255 {
+		attrs += " fill-opacity=\"" + svgNum(float64(n.A)/255.0) + "\""
+	}
+	return attrs
+}
+
+func svgColor(c color.NRGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+/* Formats a pixel coordinate with 2 decimals at most */
+func svgNum(v float64) string {
+	return fmt.Sprintf("%g", math.Round(v*100.0)/100.0)
+}
@@ -0,0 +1,107 @@
+package draw
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+/* Element of a SVG image, with its attributes and its text */
+type svgElement struct {
+	name  string
+	attrs map[string]string
+	text  string
+}
+
+func (e svgElement) num(t *testing.T, attr string) float64 {
+	t.Helper()
+	v, err := strconv.ParseFloat(e.attrs[attr], 64)
+	if err != nil {
+		t.Fatalf("Attribute %s of %s: %v", attr, e.name, err)
+	}
+	return v
+}
+
+/* Parses the SVG image, failing the test if it is not valid XML */
+func parseSvg(t *testing.T, svg []byte) []svgElement {
+	t.Helper()
+	d := xml.NewDecoder(bytes.NewReader(svg))
+	elements := make([]svgElement, 0)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return elements
+		} else if err != nil {
+			t.Fatalf("Invalid SVG: %v\n%s", err, svg)
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			e := svgElement{tok.Name.Local, make(map[string]string), ""}
+			for _, a := range tok.Attr {
+				e.attrs[a.Name.Local] = a.Value
+			}
+			elements = append(elements, e)
+		case xml.CharData:
+			if len(elements) > 0 {
+				elements[len(elements)-1].text += string(tok)
+			}
+		}
+	}
+}
+
+func TestSvgTreeDrawer(t *testing.T) {
+	const width, height, margin = 400, 300, 10
+	tests := []struct {
+		layout func(TreeDrawer) TreeLayout
+		lines  int
+	}{
+		{func(d TreeDrawer) TreeLayout { return NewNormalLayout(d, true, true, false, false) }, 6},
+		{func(d TreeDrawer) TreeLayout { return NewCircularLayout(d, true, true, false, false) }, 4},
+		{func(d TreeDrawer) TreeLayout { return NewUnrootedLayout(d, true, true, false, false, false) }, 4},
+	}
+	for i, test := range tests {
+		var buf bytes.Buffer
+		d := NewSvgTreeDrawer(&buf, width, height, margin, margin, margin, margin)
+		if err := test.layout(d).DrawTree(parse(t, "(('A<&':1,B:1):1,C:2);")); err != nil {
+			t.Fatal(err)
+		}
+		elements := parseSvg(t, buf.Bytes())
+		if elements[0].name != "svg" || elements[0].attrs["width"] != "400" || elements[0].attrs["height"] != "300" {
+			t.Errorf("Layout %d: root element %v", i, elements[0])
+		}
+		lines := 0
+		texts := make(map[string]bool)
+		for _, e := range elements {
+			switch e.name {
+			case "line":
+				lines++
+				for _, a := range []string{"x1", "x2"} {
+					if x := e.num(t, a); x < margin-1e-6 || x > width-margin+1e-6 {
+						t.Errorf("Layout %d: line outside the margins: %v", i, e.attrs)
+					}
+				}
+				for _, a := range []string{"y1", "y2"} {
+					if y := e.num(t, a); y < margin-1e-6 || y > height-margin+1e-6 {
+						t.Errorf("Layout %d: line outside the margins: %v", i, e.attrs)
+					}
+				}
+			case "text":
+				texts[strings.TrimSpace(e.text)] = true
+				if x := e.num(t, "x"); x < margin-1e-6 || x > width-margin+1e-6 {
+					t.Errorf("Layout %d: text %s outside the margins: %v", i, e.text, e.attrs)
+				}
+			}
+		}
+		if lines < test.lines {
+			t.Errorf("Layout %d: %d lines, expected at least %d", i, lines, test.lines)
+		}
+		for _, name := range []string{"A<&", "B", "C"} {
+			if !texts[name] {
+				t.Errorf("Layout %d: name %q not drawn, texts: %v", i, name, texts)
+			}
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package draw
+
+import (
+	"errors"
+	"math"
+
+	"github.com/benjamincjackson/gotree/tree"
+)
+
+/*
+Generic struct that represents a layout of two trees facing each other
+*/
+type TanglegramLayout interface {
+	DrawTrees(t1, t2 *tree.Tree) error
+	SetSupportCutoff(float64)
+	SetStyler(Styler)
+}
+
+type tanglegramLayout struct {
+	drawer           TreeDrawer
+	hasBranchLengths bool
+	hasTipLabels     bool
+	hasSupport       bool
+	untangle         bool
+	supportCutoff    float64
+	styler           Styler
+}
+
+const (
+	tanglegramGap      = 1.0 // Space between both trees, relative to their depth
+	untangleIterations = 10  // Maximum number of untangling iterations
+)
+
+/*
+TanglegramLayout initializer. Draws the first tree on the left, and the second tree
+mirrored on the right, both as in normal layouts, scaled to the same depth. Tips
+having the same name in both trees are linked by lines, drawn in the style of the
+tip of the first tree. The space between both trees is as large as each tree.
+
+If untangle is true, internal nodes of both trees are rotated before drawing, to
+reduce the number of crossing links. Rotations are done on copies of the trees:
+the trees given to DrawTrees are not modified.
+*/
+func NewTanglegramLayout(td TreeDrawer, withBranchLengths, withTipLabels, withSupportCircles, untangle bool) TanglegramLayout {
+	return &tanglegramLayout{
+		td,
+		withBranchLengths,
+		withTipLabels,
+		withSupportCircles,
+		untangle,
+		0.7,
+		nil,
+	}
+}
+
+func (layout *tanglegramLayout) SetSupportCutoff(c float64) {
+	layout.supportCutoff = c
+}
+
+func (layout *tanglegramLayout) SetStyler(s Styler) {
+	layout.styler = s
+}
+
+/*
+Draw both trees on the specific drawer. Does not close the file. The caller must do it.
+Returns an error if the trees do not have any tip in common.
+*/
+func (layout *tanglegramLayout) DrawTrees(t1, t2 *tree.Tree) error {
+	pos2 := tipPositions(t2)
+	common := false
+	for _, tip := range tipOrder(t1) {
+		if _, ok := pos2[tip.Name()]; ok {
+			common = true
+			break
+		}
+	}
+	if !common {
+		return errors.New("Trees do not have any tip in common")
+	}
+	if layout.untangle {
+		t1, t2 = t1.Clone(true), t2.Clone(true)
+		untangle(t1, t2)
+	}
+
+	left, ntips1 := layout.subLayout(t1)
+	right, ntips2 := layout.subLayout(t2)
+	ntips := math.Max(float64(ntips1), float64(ntips2))
+	// The tree having less tips is centered vertically
+	left.cache.transform(1.0, 0.0, (ntips-float64(ntips1))/2.0)
+	right.cache.transform(-1.0, 2.0+tanglegramGap, (ntips-float64(ntips2))/2.0)
+
+	// Tip names are drawn inside the figure
+	layout.drawer.SetMaxValues(2.0+tanglegramGap, ntips, 0, 0)
+	left.drawTree()
+	right.drawTree()
+	tips2 := make(map[string]*layoutPoint, len(right.cache.tipLabelPoints))
+	for _, p := range right.cache.tipLabelPoints {
+		tips2[p.name] = p
+	}
+	for _, p1 := range left.cache.tipLabelPoints {
+		if p2, ok := tips2[p1.name]; ok {
+			name1, name2 := "", ""
+			if layout.hasTipLabels {
+				name1, name2 = p1.name, p2.name
+			}
+			layout.drawer.DrawTipLink(p1.x, p1.y, p2.x, p2.y, name1, name2, p1.style)
+		}
+	}
+	layout.drawer.Write()
+	return nil
+}
+
+/*
+Normal layout of one of the trees, whose cache is filled with the tree scaled to
+a depth of 1. Returns it with the number of tips of the tree.
+*/
+func (layout *tanglegramLayout) subLayout(t *tree.Tree) (*normalLayout, int) {
+	// Tip points are always needed to link the tips
+	sub := NewNormalLayout(layout.drawer, layout.hasBranchLengths, true, false, layout.hasSupport).(*normalLayout)
+	sub.SetSupportCutoff(layout.supportCutoff)
+	sub.SetStyler(layout.styler)
+	curNbTips := 0
+	root := t.Root()
+	sub.drawTreeRecur(root, nil, tree.NIL_SUPPORT, 0, 0, &curNbTips, nodeStyle(layout.styler, root, nil, Style{}))
+	sub.hasTipLabels = layout.hasTipLabels
+	maxLength, _ := maxLength(t, layout.hasBranchLengths, false, false, nil)
+	if maxLength <= 0 {
+		maxLength = 1.0
+	}
+	sub.cache.transform(1.0/maxLength, 0.0, 0.0)
+	return sub, curNbTips
+}
+
+/*
+Rotates the internal nodes of both trees to reduce the number of crossing links:
+the children of each node are ordered by the mean position, in the other tree, of
+their tips (barycenter heuristic), alternately for both trees, until the number of
+crossings does not decrease anymore. Reorderings that increase it are reverted.
+Children having no tip in the other tree are moved after the others.
+*/
+func untangle(t1, t2 *tree.Tree) {
+	best := crossings(t1, t2)
+	for i := 0; i < untangleIterations && best > 0; i++ {
+		improved := false
+		for _, pair := range [][2]*tree.Tree{{t2, t1}, {t1, t2}} {
+			t, other := pair[0], pair[1]
+			ranks := make(map[*tree.Node]int)
+			siblingRanksRecur(t.Root(), nil, ranks)
+			barycenterSortRecur(t, t.Root(), nil, tipPositions(other))
+			if c := crossings(t1, t2); c < best {
+				best, improved = c, true
+			} else if c > best {
+				restoreOrderRecur(t, t.Root(), nil, ranks)
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+}
+
+/*
+Recursive function that orders the children of each node by the mean position of
+their tips in pos. Returns the sum of the positions of the tips of the subtree,
+and their number.
+*/
+func barycenterSortRecur(t *tree.Tree, n, prev *tree.Node, pos map[string]float64) (sum float64, count int) {
+	if n.Tip() {
+		if p, ok := pos[n.Name()]; ok {
+			return p, 1
+		}
+		return 0.0, 0
+	}
+	keys := make(map[*tree.Node]float64)
+	for _, child := range n.Neigh() {
+		if child != prev {
+			s, c := barycenterSortRecur(t, child, n, pos)
+			keys[child] = math.Inf(1)
+			if c > 0 {
+				keys[child] = s / float64(c)
+			}
+			sum += s
+			count += c
+		}
+	}
+	t.SortNeighbors(n, prev, func(n1, n2 *tree.Node) bool { return keys[n1] < keys[n2] })
+	return
+}
+
+/* Recursive function that stores the index of each node in the neighbors of its parent */
+func siblingRanksRecur(n, prev *tree.Node, ranks map[*tree.Node]int) {
+	for i, child := range n.Neigh() {
+		if child != prev {
+			ranks[child] = i
+			siblingRanksRecur(child, n, ranks)
+		}
+	}
+}
+
+/* Recursive function that restores the order of the neighbors stored by siblingRanksRecur */
+func restoreOrderRecur(t *tree.Tree, n, prev *tree.Node, ranks map[*tree.Node]int) {
+	t.SortNeighbors(n, prev, func(n1, n2 *tree.Node) bool { return ranks[n1] < ranks[n2] })
+	for _, child := range n.Neigh() {
+		if child != prev {
+			restoreOrderRecur(t, child, n, ranks)
+		}
+	}
+}
+
+/* Number of crossing links between the tips of both trees */
+func crossings(t1, t2 *tree.Tree) int {
+	pos2 := tipPositions(t2)
+	seq := make([]float64, 0, len(pos2))
+	for _, tip := range tipOrder(t1) {
+		if p, ok := pos2[tip.Name()]; ok {
+			seq = append(seq, p)
+		}
+	}
+	return inversions(seq)
+}
+
+/* Number of pairs i<j such that seq[i]>seq[j]. Sorts seq (merge sort) */
+func inversions(seq []float64) int {
+	if len(seq) < 2 {
+		return 0
+	}
+	mid := len(seq) / 2
+	left := append([]float64(nil), seq[:mid]...)
+	right := append([]float64(nil), seq[mid:]...)
+	n := inversions(left) + inversions(right)
+	i, j := 0, 0
+	for k := range seq {
+		if j >= len(right) || (i < len(left) && left[i] <= right[j]) {
+			seq[k] = left[i]
+			i++
+		} else {
+			seq[k] = right[j]
+			j++
+			n += len(left) - i
+		}
+	}
+	return n
+}
+
+/* Tips of the tree, in the order they are drawn by normal layouts */
+func tipOrder(t *tree.Tree) []*tree.Node {
+	tips := make([]*tree.Node, 0)
+	tipOrderRecur(t.Root(), nil, &tips)
+	return tips
+}
+
+func tipOrderRecur(n, prev *tree.Node, tips *[]*tree.Node) {
+	if n.Tip() {
+		*tips = append(*tips, n)
+	}
+	for _, child := range n.Neigh() {
+		if child != prev {
+			tipOrderRecur(child, n, tips)
+		}
+	}
+}
+
+/* Position of each tip name in the drawing order of the tree */
+func tipPositions(t *tree.Tree) map[string]float64 {
+	pos := make(map[string]float64)
+	for i, tip := range tipOrder(t) {
+		pos[tip.Name()] = float64(i)
+	}
+	return pos
+}
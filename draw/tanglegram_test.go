@@ -0,0 +1,97 @@
+package draw
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestInversions(t *testing.T) {
+	tests := []struct {
+		seq      []float64
+		expected int
+	}{
+		{nil, 0},
+		{[]float64{1}, 0},
+		{[]float64{1, 2, 3}, 0},
+		{[]float64{3, 2, 1}, 3},
+		{[]float64{2, 1, 4, 3}, 2},
+		{[]float64{1, 1, 0}, 2},
+	}
+	for _, test := range tests {
+		seq := append([]float64(nil), test.seq...)
+		if got := inversions(seq); got != test.expected {
+			t.Errorf("inversions(%v) = %d, expected %d", test.seq, got, test.expected)
+		}
+		if !sort.Float64sAreSorted(seq) {
+			t.Errorf("inversions(%v) does not sort the sequence: %v", test.seq, seq)
+		}
+	}
+}
+
+func TestUntangle(t *testing.T) {
+	tests := []struct {
+		nw1, nw2          string
+		before, untangled int
+	}{
+		{"((A,B),(C,D));", "((A,B),(C,D));", 0, 0},
+		{"((A,B),(C,D));", "((D,C),(B,A));", 6, 0},
+		{"(((A,B),C),(D,E));", "((E,(B,(C,A))),D);", 6, 0},
+		// F is only in the second tree
+		{"((A,B),(C,D));", "((F,(D,C)),(B,A));", 6, 0},
+	}
+	for _, test := range tests {
+		t1, t2 := parse(t, test.nw1), parse(t, test.nw2)
+		if c := crossings(t1, t2); c != test.before {
+			t.Errorf("crossings(%s, %s) = %d, expected %d", test.nw1, test.nw2, c, test.before)
+		}
+		untangle(t1, t2)
+		if c := crossings(t1, t2); c != test.untangled {
+			t.Errorf("Untangled %s, %s: %d crossings, expected %d", t1.Newick(), t2.Newick(), c, test.untangled)
+		}
+	}
+}
+
+func TestTanglegramLayout(t *testing.T) {
+	nw1, nw2 := "((A:1,B:1):1,(C:1,D:1):1);", "((D:1,C:1):1,((B:1,A:1):1,E:1):1);"
+	for _, untangle := range []bool{false, true} {
+		t1, t2 := parse(t, nw1), parse(t, nw2)
+		r := &recorder{}
+		if err := NewTanglegramLayout(r, true, true, false, untangle).DrawTrees(t1, t2); err != nil {
+			t.Fatal(err)
+		}
+		// The input trees are never modified
+		if t1.Newick() != nw1 || t2.Newick() != nw2 {
+			t.Errorf("untangle=%v: input trees modified: %s %s", untangle, t1.Newick(), t2.Newick())
+		}
+		links := r.ops("link")
+		texts := make([]string, len(links))
+		crossing := 0
+		for i, l := range links {
+			texts[i] = l.text
+			// Links go from the left tree to the mirrored right tree
+			if l.coords[0] >= l.coords[2] {
+				t.Errorf("untangle=%v: link %s from x=%v to x=%v", untangle, l.text, l.coords[0], l.coords[2])
+			}
+			for _, l2 := range links[:i] {
+				if (l.coords[1]-l2.coords[1])*(l.coords[3]-l2.coords[3]) < 0 {
+					crossing++
+				}
+			}
+		}
+		sort.Strings(texts)
+		if got := strings.Join(texts, " "); got != "A,A B,B C,C D,D" {
+			t.Errorf("untangle=%v: links %s", untangle, got)
+		}
+		if exp := map[bool]int{false: 6, true: 0}[untangle]; crossing != exp {
+			t.Errorf("untangle=%v: %d crossing links, expected %d", untangle, crossing, exp)
+		}
+		if !r.written {
+			t.Errorf("untangle=%v: drawer not written", untangle)
+		}
+	}
+	err := NewTanglegramLayout(&recorder{}, true, true, false, true).DrawTrees(parse(t, "(A,B,C);"), parse(t, "(D,E,F);"))
+	if err == nil {
+		t.Errorf("Tanglegram of trees without common tips should return an error")
+	}
+}
@@ -3,122 +3,560 @@ package draw
 import (
 	"bufio"
 	"fmt"
+	"image/color"
 	"io"
 	"log"
 	"math"
+	"strings"
 )
 
 /*
 TextTreeDrawer initializer. TextTreeDraws draws tree as ASCII on stdout or any file.
-So far: Does not take into account branch lengths.
+Horizontal branches are scaled so that the tree and the tip names fit in width
+characters. Names that do not fit in the canvas are truncated, and end with "...".
+
+If height <= 0, the height of the canvas is computed from the tree (one row per tip
+for normal layouts).
+
+Colors of the styles are rendered with ANSI escape codes (nearest of the 16 standard
+terminal colors), thick branches (width>=2) with '=', and tip symbols with
+'o', '#' and '^'.
 */
 func NewTextTreeDrawer(w io.Writer, width, height int, rightmargin int) TreeDrawer {
-	ttd := &textTreeDrawer{
+	return newTextTreeDrawer(w, width, height, rightmargin, false)
+}
+
+/*
+Same as NewTextTreeDrawer, but branches are drawn with unicode box-drawing characters
+(├, └, ─, │) instead of '+', '-' and '|', thick branches with '━', tip symbols with
+'●', '■' and '▲', and truncated names end with '…'.
+*/
+func NewUnicodeTextTreeDrawer(w io.Writer, width, height int, rightmargin int) TreeDrawer {
+	return newTextTreeDrawer(w, width, height, rightmargin, true)
+}
+
+func newTextTreeDrawer(w io.Writer, width, height int, rightmargin int, unicode bool) *textTreeDrawer {
+	if width < 1 {
+		width = 1
+	}
+	if rightmargin < 0 {
+		rightmargin = 0
+	}
+	return &textTreeDrawer{
 		w,
 		width,
 		rightmargin,
 		height,
+		unicode,
+		0,
+		nil,
+		nil,
 		nil,
 		0.0,
 		0.0,
-		0.0,
-		0.0,
-	}
-	//ttd.height = ntips * 2
-	ttd.textCanvas = make([][]rune, ttd.height)
-	for i := 0; i < len(ttd.textCanvas); i++ {
-		ttd.textCanvas[i] = make([]rune, ttd.width+ttd.rightmargin)
-		for j := 0; j < len(ttd.textCanvas[i]); j++ {
-			ttd.textCanvas[i][j] = ' '
-		}
+		0,
+		0,
 	}
-	return ttd
 }
 
+/*
+Allocates the canvas. If the height has not been given, it is computed
+from the maximum height of objects to draw (the number of tips for normal layouts).
+*/
 func (ttd *textTreeDrawer) SetMaxValues(maxLength, maxHeight float64, maxNameLength, maxNameHeight int) {
 	ttd.maxLength = maxLength
 	ttd.maxHeight = maxHeight
 	ttd.maxNameLength = maxNameLength
 	ttd.maxNameHeight = maxNameHeight
+	height := ttd.height
+	if height <= 0 {
+		height = int(math.Ceil(maxHeight)) + maxNameHeight
+	}
+	if height < 1 {
+		height = 1
+	}
+	ttd.rows = height - maxNameHeight
+	if ttd.rows < 1 {
+		ttd.rows = 1
+	}
+	ttd.textCanvas = make([][]rune, 0, height)
+	ttd.lineCanvas = make([][]uint8, 0, height)
+	ttd.colorCanvas = make([][]string, 0, height)
+	for i := 0; i < height; i++ {
+		ttd.addRow()
+	}
 }
 
 /*
 Draw a tree as ASCII in any file (stdout/stderr, etc.).
 */
 type textTreeDrawer struct {
-	outwriter     io.Writer // Output file
-	width         int       // Width of the ascii canvas
-	rightmargin   int       // Right margin of the canvas (in addition to the width)
-	height        int       // Height of the ascii canvas
-	textCanvas    [][]rune  // ascii canvas
-	maxHeight     float64   // Maximum height of object to draw (in original scale)
-	maxLength     float64   // Maximum length of object to draw (in original scale)
-	maxNameLength int       // Maximum length of species names / horitzontal
-	maxNameHeight int       // Maximum length of species names / vertical
+	outwriter     io.Writer  // Output file
+	width         int        // Width of the ascii canvas
+	rightmargin   int        // Right margin of the canvas (in addition to the width)
+	height        int        // Height of the ascii canvas (<=0: computed from the tree)
+	unicode       bool       // Draws branches with unicode box-drawing characters
+	rows          int        // Number of rows in which the tree is drawn
+	textCanvas    [][]rune   // Characters written on the canvas (names, symbols...), 0 if none
+	lineCanvas    [][]uint8  // Branch connections of each character of the canvas
+	colorCanvas   [][]string // ANSI color codes of the canvas characters ("" for default)
+	maxHeight     float64    // Maximum height of object to draw (in original scale)
+	maxLength     float64    // Maximum length of object to draw (in original scale)
+	maxNameLength int        // Maximum length of species names / horitzontal
+	maxNameHeight int        // Maximum length of species names / vertical
+}
+
+/* Connections of branches in a character of the canvas */
+const (
+	lineUp uint8 = 1 << iota
+	lineDown
+	lineLeft
+	lineRight
+	lineThick
+	lineFill // Inside a polygon
+)
+
+/* Box-drawing characters, indexed by the up/down/left/right connections */
+var boxCharacters = [16]rune{
+	' ', '│', '│', '│',
+	'─', '┘', '┐', '┤',
+	'─', '└', '┌', '├',
+	'─', '┴', '┬', '┼',
 }
 
-func (ttd *textTreeDrawer) DrawHLine(x1, x2, y float64) {
-	min := float64(ttd.width-ttd.maxNameLength) * x1 / ttd.maxLength
-	max := float64(ttd.width-ttd.maxNameLength) * x2 / ttd.maxLength
-	ypos := y * float64(ttd.height-ttd.maxNameHeight) / ttd.maxHeight
-	for i := int(min); float64(i) < max-1; i++ {
-		if i == int(min) {
-			ttd.textCanvas[int(ypos)][i] = '+'
-		} else {
-			ttd.textCanvas[int(ypos)][i] = '-'
+/* Adds an empty row at the bottom of the canvas */
+func (ttd *textTreeDrawer) addRow() {
+	ncols := ttd.width + ttd.rightmargin
+	ttd.textCanvas = append(ttd.textCanvas, make([]rune, ncols))
+	ttd.lineCanvas = append(ttd.lineCanvas, make([]uint8, ncols))
+	ttd.colorCanvas = append(ttd.colorCanvas, make([]string, ncols))
+}
+
+/*
+Column of the tree coordinate x. Branches are drawn in the columns left
+by the longest name (and the space before it), but at least in half of the width.
+*/
+func (ttd *textTreeDrawer) col(x float64) int {
+	return int(math.Round(ttd.colf(x)))
+}
+
+/* Column of the tree coordinate x, without rounding */
+func (ttd *textTreeDrawer) colf(x float64) float64 {
+	avail := ttd.width - ttd.maxNameLength - 2
+	if avail < ttd.width/2 {
+		avail = ttd.width / 2
+	}
+	if ttd.maxLength <= 0 || avail < 1 {
+		return 0
+	}
+	return x * float64(avail) / ttd.maxLength
+}
+
+/* Row of the tree coordinate y */
+func (ttd *textTreeDrawer) row(y float64) int {
+	r := int(math.Round(ttd.rowf(y)))
+	if r >= ttd.rows {
+		r = ttd.rows - 1
+	}
+	return r
+}
+
+/* Row of the tree coordinate y, without rounding */
+func (ttd *textTreeDrawer) rowf(y float64) float64 {
+	if ttd.maxHeight <= 0 {
+		return 0
+	}
+	return y * float64(ttd.rows) / ttd.maxHeight
+}
+
+/* Returns true if the cell is in the canvas */
+func (ttd *textTreeDrawer) inCanvas(row, col int) bool {
+	return row >= 0 && row < len(ttd.lineCanvas) && col >= 0 && col < len(ttd.lineCanvas[row])
+}
+
+/* Adds branch connections to a cell, if it is in the canvas */
+func (ttd *textTreeDrawer) connect(row, col int, connections uint8, color string) {
+	if ttd.inCanvas(row, col) {
+		ttd.lineCanvas[row][col] |= connections
+		ttd.colorCanvas[row][col] = color
+	}
+}
+
+/* Writes a character in a cell, if it is in the canvas */
+func (ttd *textTreeDrawer) setChar(row, col int, c rune, color string) {
+	if ttd.inCanvas(row, col) {
+		ttd.textCanvas[row][col] = c
+		ttd.colorCanvas[row][col] = color
+	}
+}
+
+func (ttd *textTreeDrawer) DrawHLine(x1, x2, y float64, style Style) {
+	c1, c2 := ttd.col(x1), ttd.col(x2)
+	if c1 > c2 {
+		c1, c2 = c2, c1
+	}
+	if c1 == c2 {
+		return
+	}
+	row := ttd.row(y)
+	thick := uint8(0)
+	if style.strokeWidth() >= 2 {
+		thick = lineThick
+	}
+	color := ansiColor(style.StrokeColor)
+	for i := c1; i <= c2; i++ {
+		connections := thick
+		if i > c1 {
+			connections |= lineLeft
 		}
+		if i < c2 {
+			connections |= lineRight
+		}
+		ttd.connect(row, i, connections, color)
 	}
 }
 
-func (ttd *textTreeDrawer) DrawVLine(x, y1, y2 float64) {
-	min := float64(ttd.height-ttd.maxNameHeight) * y1 / ttd.maxHeight
-	max := float64(ttd.height-ttd.maxNameHeight) * y2 / ttd.maxHeight
-	xpos := float64(ttd.width-ttd.maxNameLength) * x / ttd.maxLength
-	for i := int(min); float64(i) < max; i++ {
-		if i == int(min) || i == int(max) {
-			ttd.textCanvas[i][int(xpos)] = '+'
-		} else {
-			ttd.textCanvas[i][int(xpos)] = '|'
+func (ttd *textTreeDrawer) DrawVLine(x, y1, y2 float64, style Style) {
+	r1, r2 := ttd.row(y1), ttd.row(y2)
+	if r1 > r2 {
+		r1, r2 = r2, r1
+	}
+	col := ttd.col(x)
+	color := ansiColor(style.StrokeColor)
+	for i := r1; i <= r2; i++ {
+		connections := uint8(0)
+		if i > r1 {
+			connections |= lineUp
 		}
+		if i < r2 {
+			connections |= lineDown
+		}
+		ttd.connect(i, col, connections, color)
 	}
 }
 
-func (ttd *textTreeDrawer) DrawLine(x1, x2, y1, y2 float64) {
+func (ttd *textTreeDrawer) DrawLine(x1, x2, y1, y2 float64, style Style) {
 	log.Print("Method DrawLine cannot be called on textTreeDrawer: The line will not be drawn")
 }
 
-func (ttd *textTreeDrawer) DrawCurve(centerx, centery float64, middlex, middley float64, radius float64, startAngle, endAngle float64) {
+func (ttd *textTreeDrawer) DrawCurve(centerx, centery float64, middlex, middley float64, radius float64, startAngle, endAngle float64, style Style) {
 	log.Print("Method DrawCurve cannot be called on textTreeDrawer: The curve will not be drawn")
 }
 
-func (ttd *textTreeDrawer) DrawCircle(x, y float64) {
-	ypos := float64(ttd.height-ttd.maxNameHeight) * y / ttd.maxHeight
-	xpos := float64(ttd.width-ttd.maxNameLength) * x / ttd.maxLength
-	ttd.textCanvas[int(ypos)][int(xpos)] = '*'
+func (ttd *textTreeDrawer) DrawCircle(x, y float64, style Style) {
+	circle := '*'
+	if ttd.unicode {
+		circle = '•'
+	}
+	ttd.setChar(ttd.row(y), ttd.col(x), circle, ansiColor(style.StrokeColor))
+}
+
+/* Fills the characters whose center is inside the polygon. Polygons are drawn below the branches */
+func (ttd *textTreeDrawer) DrawPolygon(xs, ys []float64, style Style) {
+	if len(xs) != len(ys) || len(xs) < 3 {
+		return
+	}
+	poly := make([][2]float64, len(xs))
+	cmin, cmax, rmin, rmax := math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)
+	for i := range xs {
+		poly[i] = [2]float64{ttd.colf(xs[i]), ttd.rowf(ys[i])}
+		cmin, cmax = math.Min(cmin, poly[i][0]), math.Max(cmax, poly[i][0])
+		rmin, rmax = math.Min(rmin, poly[i][1]), math.Max(rmax, poly[i][1])
+	}
+	color := ansiColor(style.FillColor)
+	for r := int(math.Ceil(rmin)); r <= int(math.Floor(rmax)); r++ {
+		for c := int(math.Round(cmin)); c <= int(math.Round(cmax)); c++ {
+			// Cells on the left and right borders are tested slightly inside,
+			// so that the polygon ends at the same columns as the lines
+			x := math.Max(cmin+0.01, math.Min(cmax-0.01, float64(c)))
+			if insidePolygon(poly, x, float64(r)) && ttd.inCanvas(r, c) && ttd.lineCanvas[r][c] == 0 {
+				ttd.lineCanvas[r][c] |= lineFill
+				ttd.colorCanvas[r][c] = color
+			}
+		}
+	}
+}
+
+/* Draws the symbol just after the tip, the tip name being shifted by DrawName */
+func (ttd *textTreeDrawer) DrawSymbol(x, y float64, style Style) {
+	symbols := map[Symbol]rune{CircleSymbol: 'o', SquareSymbol: '#', TriangleSymbol: '^'}
+	if ttd.unicode {
+		symbols = map[Symbol]rune{CircleSymbol: '●', SquareSymbol: '■', TriangleSymbol: '▲'}
+	}
+	if symbol, ok := symbols[style.TipSymbol]; ok {
+		ttd.setChar(ttd.row(y), ttd.col(x)+1, symbol, ansiColor(style.StrokeColor))
+	}
+}
+
+/*
+Draws the name after a space (after the symbol if any). Names of tips whose incoming
+branch points to the left are drawn before the tip. Names that do not fit in the canvas
+are truncated, the truncated side being marked with "..." (or '…').
+*/
+func (ttd *textTreeDrawer) DrawName(x, y float64, name string, angle float64, style Style) {
+	runes := []rune(name)
+	start := ttd.col(x) + 2
+	if style.TipSymbol != NoSymbol {
+		start++
+	}
+	if math.Cos(angle) < 0 {
+		start = 2*ttd.col(x) - start - len(runes) + 1
+	}
+	ncols := ttd.width + ttd.rightmargin
+	if start+len(runes) <= 0 || start >= ncols {
+		return
+	}
+	if start < 0 {
+		runes = ttd.ellipsis(runes[-start:], true)
+		start = 0
+	}
+	if start+len(runes) > ncols {
+		runes = ttd.ellipsis(runes[:ncols-start], false)
+	}
+	row := ttd.row(y)
+	color := ansiColor(style.LabelColor)
+	for i, c := range runes {
+		ttd.setChar(row, start+i, c, color)
+	}
+}
+
+/*
+Replaces the last characters of the truncated name (the first ones if
+leading is true) by the ellipsis: "..." (fewer dots if the name is shorter)
+or '…' in unicode mode.
+*/
+func (ttd *textTreeDrawer) ellipsis(name []rune, leading bool) []rune {
+	n := 3
+	if ttd.unicode {
+		n = 1
+	}
+	if n > len(name) {
+		n = len(name)
+	}
+	truncated := make([]rune, len(name))
+	copy(truncated, name)
+	for i := 0; i < n; i++ {
+		pos := len(truncated) - 1 - i
+		if leading {
+			pos = i
+		}
+		truncated[pos] = '.'
+		if ttd.unicode {
+			truncated[pos] = '…'
+		}
+	}
+	return truncated
 }
 
-func (ttd *textTreeDrawer) DrawName(x, y float64, name string, angle float64) {
-	ypos := float64(ttd.height-ttd.maxNameHeight) * y / ttd.maxHeight
-	xpos := float64(ttd.width-ttd.maxNameLength) * x / ttd.maxLength
-	for i, c := range []rune(name) {
-		if int(math.Ceil(xpos))+i < len(ttd.textCanvas[int(ypos)]) {
-			ttd.textCanvas[int(ypos)][int(math.Ceil(xpos))+i] = c
+/*
+Draws the link with '-', '/' and '\' characters (or '─', '╱' and '╲'), one per
+column, leaving a space after name1 and before name2.
+*/
+func (ttd *textTreeDrawer) DrawTipLink(x1, y1, x2, y2 float64, name1, name2 string, style Style) {
+	c1 := ttd.col(x1) + 1
+	if name1 != "" {
+		c1 += len([]rune(name1)) + 2
+	}
+	c2 := ttd.col(x2) - 1
+	if name2 != "" {
+		c2 -= len([]rune(name2)) + 2
+	}
+	if c1 > c2 {
+		return
+	}
+	r1, r2 := ttd.rowf(y1), ttd.rowf(y2)
+	chars := [3]rune{'/', '-', '\\'}
+	if ttd.unicode {
+		chars = [3]rune{'╱', '─', '╲'}
+	}
+	color := ansiColor(style.StrokeColor)
+	prev := int(math.Round(r1))
+	for c := c1; c <= c2; c++ {
+		r := r1
+		if c2 > c1 {
+			r += (r2 - r1) * float64(c-c1) / float64(c2-c1)
+		}
+		row := int(math.Round(r))
+		char := chars[1]
+		if row > prev {
+			char = chars[2]
+		} else if row < prev {
+			char = chars[0]
 		}
+		if ttd.inCanvas(row, c) && ttd.textCanvas[row][c] == 0 {
+			ttd.setChar(row, c, char, color)
+		}
+		prev = row
 	}
 }
+
+/*
+Draws the axis on two new rows at the bottom of the canvas: the axis line,
+with tick marks, and the labels centered below the ticks. Labels
+that would overlap the previous one are not drawn.
+*/
+func (ttd *textTreeDrawer) DrawAxis(x1, x2, y float64, ticks []AxisTick, style Style) {
+	ttd.addRow()
+	ttd.addRow()
+	linerow, labelrow := len(ttd.textCanvas)-2, len(ttd.textCanvas)-1
+	line, tick := '-', '+'
+	if ttd.unicode {
+		line, tick = '─', '┬'
+	}
+	for i := ttd.col(x1); i <= ttd.col(x2); i++ {
+		ttd.setChar(linerow, i, line, ansiColor(style.StrokeColor))
+	}
+	last := -1
+	for _, t := range ticks {
+		pos := ttd.col(t.X)
+		if t.Mark {
+			ttd.setChar(linerow, pos, tick, ansiColor(style.StrokeColor))
+		}
+		label := []rune(t.Label)
+		start := pos - len(label)/2
+		if start < 0 {
+			start = 0
+		}
+		if len(label) == 0 || start <= last || start+len(label) > ttd.width+ttd.rightmargin {
+			continue
+		}
+		for i, c := range label {
+			ttd.setChar(labelrow, start+i, c, ansiColor(style.LabelColor))
+		}
+		last = start + len(label)
+	}
+}
+
+/*
+Draws the legend on new rows at the bottom of the canvas: the title, followed
+by a colored block and the label of each entry. Entries that do not fit in the
+width are written on the next rows.
+*/
+func (ttd *textTreeDrawer) DrawLegend(x, y float64, title string, entries []LegendEntry) {
+	block := '#'
+	if ttd.unicode {
+		block = '█'
+	}
+	ttd.addRow()
+	row := len(ttd.textCanvas) - 1
+	start := ttd.col(x)
+	pos := start
+	for _, c := range title + ":" {
+		ttd.setChar(row, pos, c, "")
+		pos++
+	}
+	for _, e := range entries {
+		label := []rune(e.Label)
+		if pos+len(label)+3 > ttd.width+ttd.rightmargin && pos > start+len([]rune(title))+1 {
+			ttd.addRow()
+			row++
+			pos = start + len([]rune(title)) + 1
+		}
+		ttd.setChar(row, pos+1, block, ansiColor(e.Color))
+		for i, c := range label {
+			ttd.setChar(row, pos+3+i, c, "")
+		}
+		pos += len(label) + 4
+	}
+}
+
+/* Character of a cell: the written character if any, or the branch character */
+func (ttd *textTreeDrawer) char(row, col int) rune {
+	if c := ttd.textCanvas[row][col]; c != 0 {
+		return c
+	}
+	connections := ttd.lineCanvas[row][col]
+	box := connections & (lineUp | lineDown | lineLeft | lineRight)
+	if box == 0 {
+		if connections&lineFill != 0 {
+			if ttd.unicode {
+				return '▒'
+			}
+			return '#'
+		}
+		return ' '
+	}
+	if ttd.unicode {
+		if box == lineLeft|lineRight || box == lineLeft || box == lineRight {
+			if connections&lineThick != 0 {
+				return '━'
+			}
+		}
+		return boxCharacters[box]
+	}
+	switch box {
+	case lineLeft, lineRight, lineLeft | lineRight:
+		if connections&lineThick != 0 {
+			return '='
+		}
+		return '-'
+	case lineUp, lineDown, lineUp | lineDown:
+		return '|'
+	}
+	return '+'
+}
+
 func (ttd *textTreeDrawer) Write() {
 	// Create Buffered Writer from io.writer
 	b := bufio.NewWriter(ttd.outwriter)
-	for _, l := range ttd.textCanvas {
-		for _, c := range l {
-			b.WriteString(fmt.Sprintf("%c", c))
+	for i := range ttd.textCanvas {
+		// Trailing spaces are not written, even if colored
+		end := len(ttd.textCanvas[i])
+		for end > 0 && ttd.char(i, end-1) == ' ' {
+			end--
+		}
+		var line strings.Builder
+		cur := ""
+		for j := 0; j < end; j++ {
+			if color := ttd.colorCanvas[i][j]; color != cur {
+				if color == "" {
+					line.WriteString(ansiReset)
+				} else {
+					line.WriteString(color)
+				}
+				cur = color
+			}
+			line.WriteRune(ttd.char(i, j))
+		}
+		if cur != "" {
+			line.WriteString(ansiReset)
 		}
+		b.WriteString(line.String())
 		b.WriteString("\n")
 	}
 	_ = b.Flush()
 }
 
 func (ttd *textTreeDrawer) Bounds() (width, height int) {
-	width, height = ttd.width, ttd.height
+	width, height = ttd.width, len(ttd.textCanvas)
 	return
 }
+
+const ansiReset = "\x1b[0m"
+
+/* The 16 standard terminal colors (xterm values), and their ANSI codes */
+var ansiPalette = []struct {
+	r, g, b uint8
+	code    int
+}{
+	{0, 0, 0, 30}, {205, 0, 0, 31}, {0, 205, 0, 32}, {205, 205, 0, 33},
+	{0, 0, 238, 34}, {205, 0, 205, 35}, {0, 205, 205, 36}, {229, 229, 229, 37},
+	{127, 127, 127, 90}, {255, 0, 0, 91}, {0, 255, 0, 92}, {255, 255, 0, 93},
+	{92, 92, 255, 94}, {255, 0, 255, 95}, {0, 255, 255, 96}, {255, 255, 255, 97},
+}
+
+/* Returns the ANSI escape code of the nearest terminal color, "" for the default color (nil) */
+func ansiColor(c color.Color) string {
+	if c == nil {
+		return ""
+	}
+	n := rgba(c)
+	best, bestdist := 0, math.Inf(1)
+	for i, p := range ansiPalette {
+		dr, dg, db := float64(n.R)-float64(p.r), float64(n.G)-float64(p.g), float64(n.B)-float64(p.b)
+		if d := dr*dr + dg*dg + db*db; d < bestdist {
+			best, bestdist = i, d
+		}
+	}
+	return fmt.Sprintf("\x1b[%dm", ansiPalette[best].code)
+}
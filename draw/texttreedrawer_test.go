@@ -0,0 +1,99 @@
+package draw
+
+import (
+	"bytes"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestTextTreeDrawer(t *testing.T) {
+	nw := "((Averylongname:1,B:1):1,C:0.5);"
+	tests := []struct {
+		width    int
+		unicode  bool
+		expected string
+	}{
+		{30, false, "" +
+			"        +------- Averylongname\n" +
+			"+-------+------- B\n" +
+			"+---- C\n"},
+		{30, true, "" +
+			"        ┌─────── Averylongname\n" +
+			"┌───────┴─────── B\n" +
+			"└──── C\n"},
+		// Branches use half of the width, and names are truncated
+		{12, false, "" +
+			"   +--- A...\n" +
+			"+--+--- B\n" +
+			"+-- C\n"},
+		{12, true, "" +
+			"   ┌─── Ave…\n" +
+			"┌──┴─── B\n" +
+			"└── C\n"},
+		{5, false, "" +
+			" +- .\n" +
+			"++- B\n" +
+			"+- C\n"},
+		{1, false, "" +
+			"|\n" +
+			"|\n" +
+			"|\n"},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		var d TreeDrawer
+		if test.unicode {
+			d = NewUnicodeTextTreeDrawer(&buf, test.width, 0, 0)
+		} else {
+			d = NewTextTreeDrawer(&buf, test.width, 0, 0)
+		}
+		if err := NewNormalLayout(d, true, true, false, false).DrawTree(parse(t, nw)); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != test.expected {
+			t.Errorf("Width %d, unicode %v:\n%s\nexpected:\n%s", test.width, test.unicode, buf.String(), test.expected)
+		}
+	}
+}
+
+func TestTextTreeDrawerColors(t *testing.T) {
+	var buf bytes.Buffer
+	layout := NewNormalLayout(NewTextTreeDrawer(&buf, 20, 0, 0), true, true, false, false)
+	red := Style{StrokeColor: color.NRGBA{255, 0, 0, 255}, LabelColor: color.NRGBA{0, 0, 250, 255}}
+	layout.SetStyler(NewRuleStyler([]StyleRule{{"c", "r", red}}, true))
+	if err := layout.DrawTree(parse(t, "((A:1,B:1)[&c=r]:1,C:0.5);")); err != nil {
+		t.Fatal(err)
+	}
+	expected := "" +
+		"         \x1b[91m+--------\x1b[0m \x1b[34mA\x1b[0m\n" +
+		"+\x1b[91m--------+--------\x1b[0m \x1b[34mB\x1b[0m\n" +
+		"+---- C\n"
+	if buf.String() != expected {
+		t.Errorf("Colored tree:\n%q\nexpected:\n%q", buf.String(), expected)
+	}
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.HasSuffix(strings.TrimSuffix(line, ansiReset), " ") {
+			t.Errorf("Line with trailing spaces: %q", line)
+		}
+	}
+}
+
+func TestAnsiColor(t *testing.T) {
+	tests := []struct {
+		c        color.Color
+		expected string
+	}{
+		{nil, ""},
+		{color.Black, "\x1b[30m"},
+		{color.White, "\x1b[97m"},
+		{color.NRGBA{250, 10, 10, 255}, "\x1b[91m"},
+		{color.NRGBA{0, 100, 0, 255}, "\x1b[30m"},
+		{color.NRGBA{0, 160, 0, 255}, "\x1b[32m"},
+	}
+	for _, test := range tests {
+		if got := ansiColor(test.c); got != test.expected {
+			t.Errorf("ansiColor(%v) = %q, expected %q", test.c, got, test.expected)
+		}
+	}
+}
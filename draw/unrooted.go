@@ -0,0 +1,335 @@
+package draw
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"github.com/benjamincjackson/gotree/tree"
+)
+
+type unrootedLayout struct {
+	drawer                 TreeDrawer
+	hasBranchLengths       bool
+	hasTipLabels           bool
+	hasInternalNodeLabels  bool
+	hasInternalNodeSymbols bool
+	hasNodeComments        bool
+	hasSupport             bool
+	hasDaylight            bool
+	supportCutoff          float64
+	styler                 Styler
+	axes                   axisOptions
+	cache                  *layoutCache
+}
+
+/* Position of a node in the unrooted layout */
+type unrootedNode struct {
+	node     *tree.Node
+	parent   *unrootedNode
+	children []*unrootedNode
+	x, y     float64
+	length   float64 // length of the branch from the parent
+	support  float64 // support of the branch from the parent
+	ntips    int     // number of tips below the node
+	style    Style   // style of the node and of the branch from the parent
+}
+
+/* Number of daylight optimization passes over all nodes */
+const daylightIterations = 5
+
+/*
+Unrooted layout: node positions are computed with the equal-angle algorithm,
+each subtree being given an angle proportional to its number of tips. If
+withDaylight is true, positions are then improved with the daylight algorithm
+(Felsenstein, Inferring phylogenies, 2004), that rotates the subtrees around each
+internal node so that the angles between them are equal.
+
+Branches are only drawn with DrawLine.
+*/
+func NewUnrootedLayout(td TreeDrawer, withBranchLengths, withTipLabels, withInternalNodeLabel, withSupportCircles, withDaylight bool) TreeLayout {
+	return &unrootedLayout{
+		td,
+		withBranchLengths,
+		withTipLabels,
+		withInternalNodeLabel,
+		false,
+		false,
+		withSupportCircles,
+		withDaylight,
+		0.7,
+		nil,
+		axisOptions{},
+		newLayoutCache(),
+	}
+}
+
+func (layout *unrootedLayout) SetSupportCutoff(c float64) {
+	layout.supportCutoff = c
+}
+
+func (layout *unrootedLayout) SetDisplayInternalNodes(s bool) {
+	layout.hasInternalNodeSymbols = s
+}
+
+func (layout *unrootedLayout) SetDisplayNodeComments(s bool) {
+	layout.hasNodeComments = s
+}
+
+func (layout *unrootedLayout) SetStyler(s Styler) {
+	layout.styler = s
+}
+
+func (layout *unrootedLayout) SetScaleBar(show bool, unit string) {
+	layout.axes.scaleBar = show
+	layout.axes.scaleBarUnit = unit
+}
+
+func (layout *unrootedLayout) SetTimeAxis(mostRecent time.Time, unitsPerYear float64) {
+	log.Print("Time axis is not supported by the unrooted layout: It will not be drawn")
+}
+
+func (layout *unrootedLayout) SetCollapse(c CladeCollapse) {
+	log.Print("Collapsed clades are not supported by the unrooted layout: They will not be collapsed")
+}
+
+func (layout *unrootedLayout) SetHeatmap(m *TipMetadata) {
+	log.Print("Heatmap is not supported by the unrooted layout: It will not be drawn")
+}
+
+/*
+Draw the tree on the specific drawer. Does not close the file. The caller must do it.
+*/
+func (layout *unrootedLayout) DrawTree(t *tree.Tree) error {
+	var err error = nil
+	root := layout.buildRecur(t.Root(), nil, nil, tree.NIL_LENGTH, tree.NIL_SUPPORT, nodeStyle(layout.styler, t.Root(), nil, Style{}))
+	layout.equalAngleRecur(root, 0.0, 2.0*math.Pi, root.ntips)
+	if layout.hasDaylight {
+		for i := 0; i < daylightIterations; i++ {
+			layout.daylightRecur(root, root)
+		}
+	}
+	_, maxName := maxLength(t, layout.hasBranchLengths, layout.hasTipLabels, layout.hasNodeComments, nil)
+
+	// Translates the tree so that all coordinates are positive
+	xmin, ymin, xmax, ymax := root.x, root.y, root.x, root.y
+	for _, n := range allUnrootedNodes(root, nil) {
+		xmin, ymin = math.Min(xmin, n.x), math.Min(ymin, n.y)
+		xmax, ymax = math.Max(xmax, n.x), math.Max(ymax, n.y)
+	}
+	layout.fillCacheRecur(root, xmin, ymin)
+	layout.drawer.SetMaxValues(xmax-xmin, ymax-ymin, maxName, maxName)
+	layout.drawTree()
+	layout.axes.draw(layout.drawer, ymax-ymin, xmax-xmin, layout.hasBranchLengths)
+	layout.drawer.Write()
+	return err
+}
+
+/*
+Recursive function that builds the layout structure of the tree, and
+counts the number of tips below each node
+*/
+func (layout *unrootedLayout) buildRecur(n *tree.Node, prev *tree.Node, parent *unrootedNode, length, support float64, style Style) *unrootedNode {
+	if !layout.hasBranchLengths || length == tree.NIL_LENGTH {
+		length = 1.0
+	}
+	un := &unrootedNode{n, parent, make([]*unrootedNode, 0, len(n.Neigh())), 0.0, 0.0, length, support, 0, style}
+	if n.Tip() && prev != nil {
+		un.ntips = 1
+	}
+	for i, child := range n.Neigh() {
+		if child != prev {
+			e := n.Edges()[i]
+			c := layout.buildRecur(child, n, un, e.Length(), e.Support(), nodeStyle(layout.styler, child, e, style))
+			un.children = append(un.children, c)
+			un.ntips += c.ntips
+		}
+	}
+	return un
+}
+
+/*
+Recursive function that places the children of the node in the wedge
+starting at angle start, each child being given an angle proportional
+to its number of tips
+*/
+func (layout *unrootedLayout) equalAngleRecur(n *unrootedNode, start, wedge float64, ntips int) {
+	for _, c := range n.children {
+		cwedge := wedge * float64(c.ntips) / float64(n.ntips)
+		angle := start + cwedge/2.0
+		c.x = n.x + c.length*math.Cos(angle)
+		c.y = n.y + c.length*math.Sin(angle)
+		layout.equalAngleRecur(c, start, cwedge, ntips)
+		start += cwedge
+	}
+}
+
+/*
+Recursive function that applies one pass of the daylight algorithm on all
+internal nodes, in pre-order: subtrees around each node are rotated so that
+the angles between them (daylight) are equal. The subtree containing the
+parent of the node is kept fixed.
+*/
+func (layout *unrootedLayout) daylightRecur(n *unrootedNode, root *unrootedNode) {
+	if len(n.children) == 0 {
+		return
+	}
+	// Subtrees around n, in angular order, starting with the fixed one
+	subtrees := make([][]*unrootedNode, 0, len(n.children)+1)
+	directions := make([]*unrootedNode, 0, len(n.children)+1)
+	if n.parent != nil {
+		subtrees = append(subtrees, allUnrootedNodes(root, n))
+		directions = append(directions, n.parent)
+	}
+	for _, c := range n.children {
+		subtrees = append(subtrees, allUnrootedNodes(c, nil))
+		directions = append(directions, c)
+	}
+	if len(subtrees) > 2 {
+		// Angular extent of each subtree, seen from n
+		lefts := make([]float64, len(subtrees))
+		rights := make([]float64, len(subtrees))
+		total := 0.0
+		for i, s := range subtrees {
+			base := math.Atan2(directions[i].y-n.y, directions[i].x-n.x)
+			min, max := 0.0, 0.0
+			for _, p := range s {
+				if p.x == n.x && p.y == n.y {
+					continue
+				}
+				a := normalizeAngle(math.Atan2(p.y-n.y, p.x-n.x) - base)
+				min, max = math.Min(min, a), math.Max(max, a)
+			}
+			lefts[i], rights[i] = base+min, base+max
+			total += max - min
+		}
+		if daylight := 2.0*math.Pi - total; daylight > 0 {
+			gap := daylight / float64(len(subtrees))
+			cur := rights[0]
+			for i := 1; i < len(subtrees); i++ {
+				// Left bound of the subtree, after the current angle
+				left := lefts[i]
+				for left < cur {
+					left += 2.0 * math.Pi
+				}
+				for left >= cur+2.0*math.Pi {
+					left -= 2.0 * math.Pi
+				}
+				delta := cur + gap - left
+				for _, p := range subtrees[i] {
+					p.x, p.y = rotatePoint(p.x, p.y, n.x, n.y, delta)
+				}
+				cur = left + delta + (rights[i] - lefts[i])
+			}
+		}
+	}
+	for _, c := range n.children {
+		layout.daylightRecur(c, root)
+	}
+}
+
+/*
+Recursive function that fills the cache with the branches, tips and
+internal nodes, translated by (-xmin,-ymin)
+*/
+func (layout *unrootedLayout) fillCacheRecur(n *unrootedNode, xmin, ymin float64) {
+	angle := 0.0
+	if n.parent != nil {
+		angle = math.Atan2(n.y-n.parent.y, n.x-n.parent.x)
+		line := &layoutLine{
+			&layoutPoint{n.parent.x - xmin, n.parent.y - ymin, angle, "", "", n.style},
+			&layoutPoint{n.x - xmin, n.y - ymin, angle, "", "", n.style},
+			n.support,
+			n.style,
+		}
+		layout.cache.branchPaths = append(layout.cache.branchPaths, line)
+	}
+	point := &layoutPoint{n.x - xmin, n.y - ymin, angle, n.node.Name(), n.node.CommentsString(), n.style}
+	if len(n.children) == 0 {
+		if layout.hasTipLabels {
+			layout.cache.tipLabelPoints = append(layout.cache.tipLabelPoints, point)
+		}
+		if n.style.TipSymbol != NoSymbol {
+			layout.cache.symbolPoints = append(layout.cache.symbolPoints, point)
+		}
+	} else {
+		layout.cache.nodePoints = append(layout.cache.nodePoints, point)
+	}
+	for _, c := range n.children {
+		layout.fillCacheRecur(c, xmin, ymin)
+	}
+}
+
+func (layout *unrootedLayout) drawTree() {
+	for _, l := range layout.cache.branchPaths {
+		layout.drawer.DrawLine(l.p1.x, l.p1.y, l.p2.x, l.p2.y, l.style)
+	}
+	for _, p := range layout.cache.symbolPoints {
+		layout.drawer.DrawSymbol(p.x, p.y, p.style)
+	}
+	if layout.hasTipLabels {
+		for _, p := range layout.cache.tipLabelPoints {
+			if layout.hasNodeComments {
+				layout.drawer.DrawName(p.x, p.y, p.name+p.comment, p.brAngle, p.style)
+			} else {
+				layout.drawer.DrawName(p.x, p.y, p.name, p.brAngle, p.style)
+			}
+		}
+	}
+	if layout.hasInternalNodeLabels {
+		for _, p := range layout.cache.nodePoints {
+			layout.drawer.DrawName(p.x, p.y, p.name, p.brAngle, p.style)
+		}
+	} else if layout.hasNodeComments {
+		for _, p := range layout.cache.nodePoints {
+			layout.drawer.DrawName(p.x, p.y, p.comment, p.brAngle, p.style)
+		}
+	}
+
+	if layout.hasInternalNodeSymbols {
+		for _, p := range layout.cache.nodePoints {
+			layout.drawer.DrawCircle(p.x, p.y, p.style)
+		}
+	}
+	for _, l := range layout.cache.branchPaths {
+		middlex := (l.p1.x + l.p2.x) / 2.0
+		middley := (l.p1.y + l.p2.y) / 2.0
+		if layout.hasSupport && l.support != tree.NIL_SUPPORT && l.support >= layout.supportCutoff {
+			layout.drawer.DrawCircle(middlex, middley, l.style)
+		}
+	}
+}
+
+/* Returns all the nodes below n (included), except the subtree of the node excluded */
+func allUnrootedNodes(n *unrootedNode, excluded *unrootedNode) []*unrootedNode {
+	nodes := make([]*unrootedNode, 0)
+	stack := []*unrootedNode{n}
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if cur == excluded {
+			continue
+		}
+		nodes = append(nodes, cur)
+		stack = append(stack, cur.children...)
+	}
+	return nodes
+}
+
+/* Normalizes the angle in ]-Pi,Pi] */
+func normalizeAngle(a float64) float64 {
+	for a > math.Pi {
+		a -= 2.0 * math.Pi
+	}
+	for a <= -math.Pi {
+		a += 2.0 * math.Pi
+	}
+	return a
+}
+
+/* Rotates the point (x,y) around (cx,cy) by the given angle */
+func rotatePoint(x, y, cx, cy, angle float64) (float64, float64) {
+	cos, sin := math.Cos(angle), math.Sin(angle)
+	dx, dy := x-cx, y-cy
+	return cx + dx*cos - dy*sin, cy + dx*sin + dy*cos
+}
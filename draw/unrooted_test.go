@@ -0,0 +1,63 @@
+package draw
+
+import (
+	"math"
+	"sort"
+	"testing"
+)
+
+/* Returns true if the segments p1p2 and p3p4 cross, excluding their ends */
+func segmentsCross(p1x, p1y, p2x, p2y, p3x, p3y, p4x, p4y float64) bool {
+	d := (p2x-p1x)*(p4y-p3y) - (p2y-p1y)*(p4x-p3x)
+	if math.Abs(d) < 1e-12 {
+		return false
+	}
+	u := ((p3x-p1x)*(p4y-p3y) - (p3y-p1y)*(p4x-p3x)) / d
+	v := ((p3x-p1x)*(p2y-p1y) - (p3y-p1y)*(p2x-p1x)) / d
+	return u > 1e-9 && u < 1-1e-9 && v > 1e-9 && v < 1-1e-9
+}
+
+func TestUnrootedLayout(t *testing.T) {
+	nw := "((A:1,B:2)0.9:1,C:3,((D:1,E:1):0.5,(F:2,G:0.1):1)0.5:2);"
+	for _, daylight := range []bool{false, true} {
+		r := &recorder{}
+		l := NewUnrootedLayout(r, true, true, false, true, daylight)
+		if err := l.DrawTree(parse(t, nw)); err != nil {
+			t.Fatal(err)
+		}
+		lines := r.ops("line")
+		lengths := make([]float64, len(lines))
+		for i, c := range lines {
+			lengths[i] = math.Hypot(c.coords[2]-c.coords[0], c.coords[3]-c.coords[1])
+			if c.coords[0] < -1e-9 || c.coords[1] < -1e-9 || c.coords[2] < -1e-9 || c.coords[3] < -1e-9 {
+				t.Errorf("Daylight %v: negative coordinates %v", daylight, c.coords)
+			}
+		}
+		sort.Float64s(lengths)
+		expected := []float64{0.1, 0.5, 1, 1, 1, 1, 1, 2, 2, 2, 3}
+		if len(lengths) != len(expected) {
+			t.Fatalf("Daylight %v: %d branches drawn, expected %d", daylight, len(lengths), len(expected))
+		}
+		for i := range expected {
+			if !near(lengths[i], expected[i]) {
+				t.Errorf("Daylight %v: branch lengths %v, expected %v", daylight, lengths, expected)
+				break
+			}
+		}
+		for i, l1 := range lines {
+			for _, l2 := range lines[i+1:] {
+				c1, c2 := l1.coords, l2.coords
+				if segmentsCross(c1[0], c1[1], c1[2], c1[3], c2[0], c2[1], c2[2], c2[3]) {
+					t.Errorf("Daylight %v: branches %v and %v cross", daylight, c1, c2)
+				}
+			}
+		}
+		if n := len(r.names()); n != 7 {
+			t.Errorf("Daylight %v: %d names drawn, expected 7", daylight, n)
+		}
+		// Only the AB branch has a support >= 0.7
+		if n := len(r.ops("circle")); n != 1 {
+			t.Errorf("Daylight %v: %d support circles drawn, expected 1", daylight, n)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package newick
+
+import (
+	"fmt"
+)
+
+// SyntaxError is returned by the parser when the input is not a valid
+// Newick tree. It gives the position of the offending token in the input.
+type SyntaxError struct {
+	Msg      string // Description of the error
+	Token    string // Offending token
+	Context  string // Input text around the offending token
+	Position        // Position of the offending token
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("Newick Error: %s, at line %d, column %d (offset %d), token %q, near %q",
+		e.Msg, e.Line, e.Column, e.Offset, e.Token, e.Context)
+}
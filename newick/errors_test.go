@@ -0,0 +1,63 @@
+package newick
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSyntaxError(t *testing.T) {
+	tests := []struct {
+		nw      string
+		msg     string
+		token   string
+		context string
+		pos     Position
+	}{
+		{"((A,B),\n(C,D)x:y);", "No numeric value after :", "y", "((A,B), (C,D)x:y);", Position{15, 2, 8}},
+		{"((A,B),C)];", "Mismatched ]", "]", "((A,B),C)];", Position{9, 1, 10}},
+		{"((A,B),C)", "Expected ; at the end of the tree", "", "((A,B),C)", Position{9, 1, 10}},
+		{"((A,B),C));", "Mismatched parenthesis at ;", ";", "((A,B),C));", Position{10, 1, 11}},
+		{"A;", "Expected ( at the start of the tree", "A", "A;", Position{0, 1, 1}},
+		{"((A,'B),C);", "Missing closing quote", "'B),C);", "((A,'B),C);", Position{4, 1, 5}},
+		{"((A,B),C[x);", "Unmatched bracket", "[", "((A,B),C[x);", Position{8, 1, 9}},
+		// Columns count characters, offsets count bytes
+		{"((A,B)éé,C):a;", "No numeric value after :", "a", "((A,B)éé,C):a;", Position{14, 1, 13}},
+		// At most 30 characters before the token
+		{"(" + strings.Repeat("X", 50) + ",B):z;", "No numeric value after :", "z", strings.Repeat("X", 26) + ",B):z;", Position{55, 1, 56}},
+	}
+	for _, test := range tests {
+		_, err := NewParser(strings.NewReader(test.nw)).Parse()
+		var serr *SyntaxError
+		if !errors.As(err, &serr) {
+			t.Errorf("Parse(%q): %v, expected a *SyntaxError", test.nw, err)
+			continue
+		}
+		if serr.Msg != test.msg || serr.Token != test.token || serr.Context != test.context || serr.Position != test.pos {
+			t.Errorf("Parse(%q): %+v, expected {Msg:%s Token:%s Context:%s Position:%+v}", test.nw, *serr, test.msg, test.token, test.context, test.pos)
+		}
+	}
+}
+
+func TestSyntaxErrorMessage(t *testing.T) {
+	err := &SyntaxError{"Mismatched ]", "]", "(A,B)];", Position{5, 1, 6}}
+	if exp := `Newick Error: Mismatched ], at line 1, column 6 (offset 5), token "]", near "(A,B)];"`; err.Error() != exp {
+		t.Errorf("Error() = %s, expected %s", err.Error(), exp)
+	}
+}
+
+func TestSyntaxErrorSuccessiveTrees(t *testing.T) {
+	// Positions are counted from the start of the stream
+	p := NewParser(strings.NewReader("((A,B),C);\n((A,B),C):x;"))
+	if _, err := p.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	_, err := p.Parse()
+	var serr *SyntaxError
+	if !errors.As(err, &serr) {
+		t.Fatalf("Second tree: %v, expected a *SyntaxError", err)
+	}
+	if exp := (Position{21, 2, 11}); serr.Position != exp {
+		t.Errorf("Second tree: error at %+v, expected %+v", serr.Position, exp)
+	}
+}
@@ -5,35 +5,104 @@ import (
 	"bytes"
 	"io"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
 // Scanner represents a lexical scanner.
 type Scanner struct {
-	r *bufio.Reader
+	r           *bufio.Reader
+	underscores bool     // Underscores of unquoted names are read as spaces
+	pos         Position // Position of the next rune to read
+	prev        Position // Position before the last read rune, restored by unread
+	tokpos      Position // Position of the last scanned token
+	recent      []rune   // Last read runes, to give the context of errors
 }
 
+// Position of a character in the input
+type Position struct {
+	Offset int64 // Byte offset, from 0
+	Line   int   // Line, from 1
+	Column int   // Column, in characters, from 1
+}
+
+// Number of characters given before and after tokens in error contexts
+const contextLength = 30
+
 // NewScanner returns a new instance of Scanner.
 func NewScanner(r io.Reader) *Scanner {
-	return &Scanner{r: bufio.NewReader(r)}
+	start := Position{0, 1, 1}
+	return &Scanner{r: bufio.NewReader(r), pos: start, prev: start, tokpos: start}
+}
+
+// Position returns the position of the first character
+// of the last token returned by Scan.
+func (s *Scanner) Position() Position {
+	return s.tokpos
+}
+
+// SetUnderscoresAsSpaces sets whether underscores of unquoted
+// names are replaced by spaces, as specified by the Newick format.
+// Underscores of quoted names are always kept.
+func (s *Scanner) SetUnderscoresAsSpaces(underscores bool) {
+	s.underscores = underscores
 }
 
 // read reads the next rune from the bufferred reader.
 // Returns the rune(0) if an error occurs (or io.EOF is returned).
 func (s *Scanner) read() rune {
-	ch, _, err := s.r.ReadRune()
+	s.prev = s.pos
+	ch, size, err := s.r.ReadRune()
 	if err != nil {
 		return eof
 	}
+	s.pos.Offset += int64(size)
+	s.pos.Column++
+	if ch == '\n' {
+		s.pos.Line++
+		s.pos.Column = 1
+	}
+	if len(s.recent) == 2*contextLength {
+		s.recent = append(s.recent[:0], s.recent[contextLength:]...)
+	}
+	s.recent = append(s.recent, ch)
 	return ch
 }
 
 // unread places the previously read rune back on the reader.
 func (s *Scanner) unread() {
-	_ = s.r.UnreadRune()
+	if s.r.UnreadRune() == nil {
+		s.pos = s.prev
+		s.recent = s.recent[:len(s.recent)-1]
+	}
+}
+
+// context returns the text around the last scanned token: at most
+// contextLength characters before it, the token, and at most
+// contextLength bytes after it. New lines and tabs are replaced by spaces.
+func (s *Scanner) context() string {
+	// Index of the first rune of the token in the recent runes
+	start := len(s.recent)
+	for size := s.pos.Offset - s.tokpos.Offset; size > 0 && start > 0; start-- {
+		size -= int64(utf8.RuneLen(s.recent[start-1]))
+	}
+	from := start - contextLength
+	if from < 0 {
+		from = 0
+	}
+	after, _ := s.r.Peek(contextLength)
+	text := string(s.recent[from:]) + string(bytes.ToValidUTF8(after, nil))
+	return strings.Map(func(ch rune) rune {
+		if isWhitespace(ch) {
+			return ' '
+		}
+		return ch
+	}, text)
 }
 
 // Scan returns the next token and literal value.
 func (s *Scanner) Scan() (tok Token, lit string) {
+	s.tokpos = s.pos
 	// Read the next rune.
 	ch := s.read()
 
@@ -61,6 +130,8 @@ func (s *Scanner) Scan() (tok Token, lit string) {
 		return EOT, string(ch)
 	case ':':
 		return STARTLEN, string(ch)
+	case '\'':
+		return s.scanQuoted()
 	}
 
 	s.unread()
@@ -108,10 +179,74 @@ func (s *Scanner) scanIdent() (tok Token, lit string) {
 		}
 	}
 
-	_, err := strconv.ParseFloat(buf.String(), 64)
+	// Whitespaces around unquoted names are not part of the name
+	lit = strings.TrimSpace(buf.String())
+	_, err := strconv.ParseFloat(lit, 64)
 	if err != nil {
-		return IDENT, buf.String()
+		if s.underscores {
+			return IDENT, strings.ReplaceAll(lit, "_", " ")
+		}
+		return IDENT, lit
 	} else {
-		return NUMERIC, buf.String()
+		return NUMERIC, lit
+	}
+}
+
+// scanQuoted consumes a quoted name, the opening quote being already read.
+// Two consecutive quotes inside the name stand for one quote.
+// Returns the name without the quotes, or ILLEGAL if the closing quote is missing.
+func (s *Scanner) scanQuoted() (tok Token, lit string) {
+	var buf bytes.Buffer
+	for {
+		ch := s.read()
+		if ch == eof {
+			return ILLEGAL, "'" + buf.String()
+		}
+		if ch == '\'' {
+			if next := s.read(); next != '\'' {
+				if next != eof {
+					s.unread()
+				}
+				return QUOTED, buf.String()
+			}
+		}
+		buf.WriteRune(ch)
+	}
+}
+
+// ScanComment consumes the content of a comment, the opening bracket
+// being already read, and the closing bracket. Quotes and whitespaces
+// are kept as is. Brackets inside quoted values of annotations (starting
+// with a quote after '=', ',' or '{', e.g. &note="see [1]") do not end
+// the comment, two consecutive quotes standing for one quote.
+// Returns false if the closing bracket is missing.
+func (s *Scanner) ScanComment() (comment string, ok bool) {
+	var buf bytes.Buffer
+	var quote rune // Quote of the current quoted value, 0 if none
+	var prev rune  // Previous rune, other than whitespace
+	for {
+		ch := s.read()
+		if ch == eof {
+			return buf.String(), false
+		}
+		switch {
+		case quote != 0 && ch == quote:
+			if next := s.read(); next == quote {
+				buf.WriteRune(ch)
+			} else {
+				quote = 0
+				if next != eof {
+					s.unread()
+				}
+			}
+		case quote == 0 && (ch == '"' || ch == '\'') && (prev == 0 || prev == '=' || prev == ',' || prev == '{'):
+			quote = ch
+		case quote == 0 && ch == ']':
+			return buf.String(), true
+		}
+		buf.WriteRune(ch)
+		if !isWhitespace(ch) {
+			prev = ch
+		}
 	}
 }
@@ -0,0 +1,67 @@
+package newick
+
+import (
+	"strings"
+	"testing"
+)
+
+type scanned struct {
+	tok Token
+	lit string
+}
+
+func TestScanner(t *testing.T) {
+	tests := []struct {
+		input       string
+		underscores bool
+		expected    []scanned
+	}{
+		{"(A,B)0.9:1;", false, []scanned{
+			{OPENPAR, "("}, {IDENT, "A"}, {NEWSIBLING, ","}, {IDENT, "B"}, {CLOSEPAR, ")"},
+			{NUMERIC, "0.9"}, {STARTLEN, ":"}, {NUMERIC, "1"}, {EOT, ";"}}},
+		{"'A/B (2020)',", false, []scanned{{QUOTED, "A/B (2020)"}, {NEWSIBLING, ","}}},
+		{"'O''Brien'", false, []scanned{{QUOTED, "O'Brien"}}},
+		{"'0.5'", false, []scanned{{QUOTED, "0.5"}}},
+		{"''", false, []scanned{{QUOTED, ""}}},
+		{"'A", false, []scanned{{ILLEGAL, "'A"}}},
+		{" A B \t,", false, []scanned{{WS, " "}, {IDENT, "A B"}, {NEWSIBLING, ","}}},
+		{"A_B,'A_B'", false, []scanned{{IDENT, "A_B"}, {NEWSIBLING, ","}, {QUOTED, "A_B"}}},
+		{"A_B,'A_B'", true, []scanned{{IDENT, "A B"}, {NEWSIBLING, ","}, {QUOTED, "A_B"}}},
+		{"A )", false, []scanned{{IDENT, "A"}, {CLOSEPAR, ")"}}},
+	}
+	for _, test := range tests {
+		s := NewScanner(strings.NewReader(test.input))
+		s.SetUnderscoresAsSpaces(test.underscores)
+		for i, exp := range test.expected {
+			if tok, lit := s.Scan(); tok != exp.tok || lit != exp.lit {
+				t.Errorf("Scan(%q), token %d: %v %q, expected %v %q", test.input, i, tok, lit, exp.tok, exp.lit)
+				break
+			}
+		}
+	}
+}
+
+func TestScanComment(t *testing.T) {
+	tests := []struct {
+		input   string
+		comment string
+		ok      bool
+	}{
+		{"&a='x,y' ]", "&a='x,y' ", true},
+		{"a(b):c;]x", "a(b):c;", true},
+		{"unclosed", "unclosed", false},
+		// Brackets in quoted values do not end the comment
+		{"&note=\"see [1]\"]x", "&note=\"see [1]\"", true},
+		{"&a={'x]', \"y\"\"]\"}]", "&a={'x]', \"y\"\"]\"}", true},
+		{"&&NHX:S='a]b']", "&&NHX:S='a]b'", true},
+		// Apostrophes in plain comments are not quotes
+		{"it's]x", "it's", true},
+		{"&a=\"x]", "&a=\"x]", false},
+	}
+	for _, test := range tests {
+		comment, ok := NewScanner(strings.NewReader(test.input)).ScanComment()
+		if comment != test.comment || ok != test.ok {
+			t.Errorf("ScanComment(%q) = %q %v, expected %q %v", test.input, comment, ok, test.comment, test.ok)
+		}
+	}
+}
@@ -1,8 +1,6 @@
 package newick
 
 import (
-	"errors"
-	"fmt"
 	"io"
 	"log"
 	"strconv"
@@ -15,9 +13,10 @@ import (
 type Parser struct {
 	s   *Scanner
 	buf struct {
-		tok Token  // last read token
-		lit string // last read literal
-		n   int    // buffer size (max=1)
+		tok Token    // last read token
+		lit string   // last read literal
+		pos Position // position of the last read token
+		n   int      // buffer size (max=1)
 	}
 }
 
@@ -26,6 +25,12 @@ func NewParser(r io.Reader) *Parser {
 	return &Parser{s: NewScanner(r)}
 }
 
+// SetUnderscoresAsSpaces sets whether underscores of unquoted names
+// are read as spaces (see Scanner.SetUnderscoresAsSpaces).
+func (p *Parser) SetUnderscoresAsSpaces(underscores bool) {
+	p.s.SetUnderscoresAsSpaces(underscores)
+}
+
 // scan returns the next token from the underlying scanner.
 // If a token has been unscanned then read that instead.
 func (p *Parser) scan() (tok Token, lit string) {
@@ -39,7 +44,7 @@ func (p *Parser) scan() (tok Token, lit string) {
 	tok, lit = p.s.Scan()
 
 	// Save it to the buffer in case we unscan later.
-	p.buf.tok, p.buf.lit = tok, lit
+	p.buf.tok, p.buf.lit, p.buf.pos = tok, lit, p.s.Position()
 
 	return
 }
@@ -47,6 +52,16 @@ func (p *Parser) scan() (tok Token, lit string) {
 // unscan pushes the previously read token back onto the buffer.
 func (p *Parser) unscan() { p.buf.n = 1 }
 
+// syntaxError returns an error located at the last read token.
+// Long tokens (e.g. unterminated quoted names) are truncated.
+func (p *Parser) syntaxError(msg string) *SyntaxError {
+	token := p.buf.lit
+	if runes := []rune(token); len(runes) > contextLength {
+		token = string(runes[:contextLength]) + "..."
+	}
+	return &SyntaxError{msg, token, p.s.context(), p.buf.pos}
+}
+
 // scanIgnoreWhitespace scans the next non-whitespace token.
 func (p *Parser) scanIgnoreWhitespace() (tok Token, lit string) {
 	tok, lit = p.scan()
@@ -57,9 +72,25 @@ func (p *Parser) scanIgnoreWhitespace() (tok Token, lit string) {
 }
 
 // Parses a Newick String.
+//
+// Names may be quoted with single quotes, two consecutive quotes
+// standing for a quote in the name, e.g.:
+//
+//	'A/B (2020)'
+//	'O''Brien'
+//
+// Quoted names are always names, even if they look like numbers.
+//
+// The parser may be called several times to read successive
+// trees from the same reader. It returns io.EOF if there is
+// no more tree to read. Syntax errors are returned as *SyntaxError.
 func (p *Parser) Parse() (newtree *tree.Tree, err error) {
 	// May have information inside [] before the tree
 	tok, lit := p.scanIgnoreWhitespace()
+	if tok == EOF {
+		err = io.EOF
+		return
+	}
 	if tok == OPENBRACK {
 		if _, err = p.consumeComment(tok, lit); err != nil {
 			return
@@ -70,7 +101,7 @@ func (p *Parser) Parse() (newtree *tree.Tree, err error) {
 
 	//Next token should be a "OPENPAR" token.
 	if tok != OPENPAR {
-		err = fmt.Errorf("found %q, expected (", lit)
+		err = p.syntaxError("Expected ( at the start of the tree")
 		return
 	}
 	p.unscan()
@@ -83,19 +114,14 @@ func (p *Parser) Parse() (newtree *tree.Tree, err error) {
 		return
 	}
 	if level != 0 {
-		err = fmt.Errorf("Newick Error : Mismatched parenthesis after parsing")
+		err = p.syntaxError("Mismatched parenthesis after parsing")
 		return
 	}
 	tok, lit = p.scanIgnoreWhitespace()
 	if tok != EOT {
-		err = fmt.Errorf("found %q, expected ;", lit)
+		err = p.syntaxError("Expected ; at the end of the tree")
 		return
 	}
-	/* Remove spaces before and after tip names */
-	for _, tip := range newtree.Tips() {
-		tip.SetName(strings.TrimSpace(tip.Name()))
-	}
-
 	//newtree.ReinitIndexes()
 	//tree.UpdateTipIndex()
 	// err = tree.ClearBitSets()
@@ -115,6 +141,7 @@ func (p *Parser) parseIter(t *tree.Tree, level *int) (prevTok Token, err error)
 	var newNode, node *tree.Node = nil, nil
 	var edge *tree.Edge = nil
 	var length, support, pval float64
+	var edgeComment bool // The last comment was added to the edge
 	prevTok = -1
 	var nedges, nnodes int = 0, 0
 	defer nodeStack.Clear()
@@ -125,7 +152,7 @@ func (p *Parser) parseIter(t *tree.Tree, level *int) (prevTok Token, err error)
 		case OPENPAR:
 			if node == nil {
 				if *level > 0 {
-					err = errors.New("Nil node at depth > 0")
+					err = p.syntaxError("Nil node at depth > 0")
 					return
 				}
 				node = t.NewNode()
@@ -135,7 +162,7 @@ func (p *Parser) parseIter(t *tree.Tree, level *int) (prevTok Token, err error)
 				t.SetRoot(node)
 			} else {
 				if *level == 0 {
-					err = errors.New("Newick Error: An open parenthesis while the stack is empty... Forgot a ';' at the end of previous tree?")
+					err = p.syntaxError("An open parenthesis while the stack is empty... Forgot a ';' at the end of previous tree?")
 					return
 				}
 				newNode = t.NewNode()
@@ -153,7 +180,7 @@ func (p *Parser) parseIter(t *tree.Tree, level *int) (prevTok Token, err error)
 			prevTok = tok
 			(*level)--
 			if _, _, err = nodeStack.Pop(); err != nil {
-				err = errors.New("Newick Error: Closing parenthesis while the stack is already empty")
+				err = p.syntaxError("Closing parenthesis while the stack is already empty")
 				return
 			}
 			node, edge, _ = nodeStack.Head()
@@ -163,38 +190,45 @@ func (p *Parser) parseIter(t *tree.Tree, level *int) (prevTok Token, err error)
 			if comment, err = p.consumeComment(tok, lit); err != nil {
 				return
 			}
-			// Add comment to edge if comment located after branch length
-			if prevTok == STARTLEN {
+			// NHX tags are annotations of the node, B being the support of its edge
+			if nhx, ok := tree.ParseNHX(comment); ok && node != nil &&
+				(prevTok == STARTLEN || prevTok == CLOSEPAR || prevTok == IDENT || prevTok == NUMERIC || prevTok == CLOSEBRACK) {
+				setNHX(node, edge, nhx)
+			} else if edge != nil && (prevTok == STARTLEN || (prevTok == CLOSEBRACK && edgeComment)) {
+				// Add comment to edge if comment located after branch length
+				// (or after another comment of the edge)
 				edge.AddComment(comment)
-			} else if (prevTok == CLOSEPAR || prevTok == IDENT || prevTok == NUMERIC || prevTok == CLOSEBRACK) && node != nil {
+				edgeComment = true
+			} else if (prevTok == CLOSEPAR || prevTok == IDENT || prevTok == NUMERIC || prevTok == CLOSEBRACK || prevTok == STARTLEN) && node != nil {
 				// Else we add comment to node
 				node.AddComment(comment)
+				edgeComment = false
 			} else {
-				err = errors.New("Newick Error: Comment should not be located here: " + lit)
+				err = p.syntaxError("Comment should not be located here")
 				return
 			}
 			prevTok = CLOSEBRACK
 		case CLOSEBRACK:
 			// Error here should not have
-			err = errors.New("Newick Error: Mismatched ] here...")
+			err = p.syntaxError("Mismatched ]")
 			return
 		case STARTLEN:
 			if tok, lit = p.scanIgnoreWhitespace(); tok != NUMERIC {
-				err = errors.New("Newick Error: No numeric value after :")
+				err = p.syntaxError("No numeric value after :")
 				return
 			}
 			// We skip length if the length is assigned to the root node
 			if node != nil && *level != 0 {
 				if edge == nil {
-					err = errors.New("Newick Error: Edge length should not be located here: " + lit)
+					err = p.syntaxError("Edge length should not be located here")
 					return
 				}
 				if edge.Length() != tree.NIL_LENGTH {
-					err = errors.New("Newick Error: More than one length is given :" + lit)
+					err = p.syntaxError("More than one length is given")
 					return
 				}
 				if length, err = strconv.ParseFloat(lit, 64); err != nil {
-					err = errors.New("Newick Error: Length is not a float value : " + lit)
+					err = p.syntaxError("Length is not a float value")
 					return
 				}
 				edge.SetLength(length)
@@ -202,18 +236,21 @@ func (p *Parser) parseIter(t *tree.Tree, level *int) (prevTok Token, err error)
 				log.Print("Newick : Branch lengths attached to root node are ignored")
 			} else {
 				// For root node, level==0, we just ignore it
-				err = errors.New("Newick Error: Cannot assign length to nil node :" + lit)
+				err = p.syntaxError("Cannot assign length to nil node")
 				return
 			}
 			prevTok = STARTLEN
 		case NEWSIBLING:
 			if _, _, err = nodeStack.Pop(); err != nil {
-				err = errors.New("Newick Error: Stack is empty, a coma should not be located here: " + lit)
+				err = p.syntaxError("Stack is empty, a coma should not be located here")
 				return
 			}
 			node, edge, _ = nodeStack.Head()
 			prevTok = NEWSIBLING
-		case IDENT, NUMERIC:
+		case ILLEGAL:
+			err = p.syntaxError("Missing closing quote")
+			return
+		case IDENT, NUMERIC, QUOTED:
 			// Here we have a node name or a bootstrap value
 			if prevTok == CLOSEPAR {
 				// Bootstrap support value (numeric)
@@ -223,6 +260,7 @@ func (p *Parser) parseIter(t *tree.Tree, level *int) (prevTok Token, err error)
 						//return -1, errors.New("Newick Error: We do not accept support value on root")
 					} else {
 						if support, err = strconv.ParseFloat(lit, 64); err != nil {
+							err = p.syntaxError("Support is not a float value")
 							return
 						}
 						edge.SetSupport(support)
@@ -231,9 +269,11 @@ func (p *Parser) parseIter(t *tree.Tree, level *int) (prevTok Token, err error)
 					// If of the form numeric/numeric => then Support value/pvalue
 					vals := strings.Split(lit, "/")
 					hasname := true
-					if len(vals) == 2 && edge != nil {
-						if support, err = strconv.ParseFloat(vals[0], 64); err == nil {
-							if pval, err = strconv.ParseFloat(vals[1], 64); err == nil {
+					if tok == IDENT && len(vals) == 2 && edge != nil {
+						// Otherwise, the node name contains a /
+						var serr, perr error
+						if support, serr = strconv.ParseFloat(vals[0], 64); serr == nil {
+							if pval, perr = strconv.ParseFloat(vals[1], 64); perr == nil {
 								edge.SetSupport(support)
 								edge.SetPValue(pval)
 								hasname = false
@@ -243,7 +283,7 @@ func (p *Parser) parseIter(t *tree.Tree, level *int) (prevTok Token, err error)
 					if hasname {
 						// Node name
 						if node == nil {
-							err = errors.New("Newick Error: Cannot assign node name to nil node: " + lit)
+							err = p.syntaxError("Cannot assign node name to nil node")
 							return
 						}
 						node.SetName(lit)
@@ -252,11 +292,11 @@ func (p *Parser) parseIter(t *tree.Tree, level *int) (prevTok Token, err error)
 			} else {
 				// Else we have a new tip
 				if prevTok != OPENPAR && prevTok != NEWSIBLING {
-					err = errors.New("Newick Error: There should not be a tip name in this context: " + lit)
+					err = p.syntaxError("There should not be a tip name in this context")
 					return
 				}
 				if node == nil {
-					err = errors.New("Cannot create a new tip with no parent: " + lit)
+					err = p.syntaxError("Cannot create a new tip with no parent")
 					return
 				}
 				newNode = t.NewNode()
@@ -273,7 +313,7 @@ func (p *Parser) parseIter(t *tree.Tree, level *int) (prevTok Token, err error)
 		case EOT:
 			p.unscan()
 			if (*level) != 0 {
-				err = errors.New("Newick Error: Mismatched parenthesis at ;")
+				err = p.syntaxError("Mismatched parenthesis at ;")
 				return
 			}
 			prevTok = tok
@@ -285,23 +325,32 @@ func (p *Parser) parseIter(t *tree.Tree, level *int) (prevTok Token, err error)
 	}
 }
 
+// Sets the NHX tags as annotations of the node, except the numeric
+// B tag, which is the support of the edge above the node (if any).
+func setNHX(node *tree.Node, edge *tree.Edge, nhx []tree.Annotation) {
+	for _, a := range nhx {
+		if a.Key == "B" && a.Value.Type == tree.NumberAnnotation && edge != nil {
+			edge.SetSupport(a.Value.Number)
+		} else {
+			node.SetAnnotation(a.Key, a.Value)
+		}
+	}
+}
+
 // Consumes comment inside brakets [comment] if the given current token is a [.
-// At the end returns the matching ] token and lit.
+// At the end returns the comment, without spaces around it, the matching ]
+// being consumed. Quotes inside comments are not interpreted.
 // If the given token is not a [, then returns an error
 func (p *Parser) consumeComment(curtoken Token, curlit string) (comment string, err error) {
 	if curtoken == OPENBRACK {
-		commenttoken, commentlit := p.scanIgnoreWhitespace()
-		for commenttoken != CLOSEBRACK {
-			if commenttoken == EOF || commenttoken == ILLEGAL {
-				err = fmt.Errorf("Unmatched bracket")
-				return
-			} else {
-				comment += commentlit
-			}
-			commenttoken, commentlit = p.scanIgnoreWhitespace()
+		var ok bool
+		if comment, ok = p.s.ScanComment(); !ok {
+			err = p.syntaxError("Unmatched bracket")
+			return
 		}
+		comment = strings.TrimSpace(comment)
 	} else {
-		err = fmt.Errorf("A comment must start with [")
+		err = p.syntaxError("A comment must start with [")
 	}
 	return
 }
@@ -0,0 +1,68 @@
+package newick
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseSuccessiveTrees(t *testing.T) {
+	p := NewParser(strings.NewReader("((A,B),C);\n(D,(E,F));\n\n"))
+	for _, exp := range []string{"((A,B),C);", "(D,(E,F));"} {
+		tr, err := p.Parse()
+		if err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		if tr.Newick() != exp {
+			t.Errorf("Parse: %s, expected %s", tr.Newick(), exp)
+		}
+	}
+	if _, err := p.Parse(); err != io.EOF {
+		t.Errorf("Parse after the last tree: %v, expected io.EOF", err)
+	}
+}
+
+func TestParseQuotedNames(t *testing.T) {
+	tests := []struct {
+		nw          string
+		underscores bool
+		names       []string // Names of the tips
+		expected    string   // Written tree
+	}{
+		{"('A/B (2020)',C);", false, []string{"A/B (2020)", "C"}, "('A/B (2020)',C);"},
+		{"('O''Brien','x,y':1);", false, []string{"O'Brien", "x,y"}, "('O''Brien','x,y':1);"},
+		{"( A ,\tB\n);", false, []string{"A", "B"}, "(A,B);"},
+		{"(A_B,'C_D');", false, []string{"A_B", "C_D"}, "('A_B','C_D');"},
+		{"(A_B,'C_D');", true, []string{"A B", "C_D"}, "('A B','C_D');"},
+		// Quoted numbers are names, not supports
+		{"((A,B)'0.9',C);", false, []string{"A", "B", "C"}, "((A,B)'0.9',C);"},
+		{"((A,B)0.9,C);", false, []string{"A", "B", "C"}, "((A,B)0.9,C);"},
+	}
+	for _, test := range tests {
+		p := NewParser(strings.NewReader(test.nw))
+		p.SetUnderscoresAsSpaces(test.underscores)
+		tr, err := p.Parse()
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.nw, err)
+			continue
+		}
+		names := make([]string, 0)
+		for _, tip := range tr.Tips() {
+			names = append(names, tip.Name())
+		}
+		if strings.Join(names, "|") != strings.Join(test.names, "|") {
+			t.Errorf("Parse(%q): tips %q, expected %q", test.nw, names, test.names)
+		}
+		if tr.Newick() != test.expected {
+			t.Errorf("Parse(%q): %s, expected %s", test.nw, tr.Newick(), test.expected)
+		}
+		// The written tree is read back identically
+		back, err := NewParser(strings.NewReader(tr.Newick())).Parse()
+		if err != nil || back.Newick() != tr.Newick() {
+			t.Errorf("Parse(%q): %s is read back as %v (%v)", test.nw, tr.Newick(), back, err)
+		}
+	}
+	if _, err := NewParser(strings.NewReader("('A,B);")).Parse(); err == nil {
+		t.Errorf("Parse of a name without closing quote should return an error")
+	}
+}
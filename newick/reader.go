@@ -0,0 +1,48 @@
+package newick
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+
+	"github.com/benjamincjackson/gotree/tree"
+)
+
+// Reader reads successive trees from a Newick stream, each tree
+// ending with a ';'. Trees are parsed one at a time, so that memory
+// usage does not depend on the number of trees of the stream.
+type Reader struct {
+	parser *Parser
+}
+
+// NewReader returns a new Reader reading from r.
+// The input is transparently decompressed if it is gzipped or bzip2ed.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(3)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		var gz *gzip.Reader
+		if gz, err = gzip.NewReader(br); err != nil {
+			return nil, err
+		}
+		return &Reader{NewParser(gz)}, nil
+	}
+	if err == nil && bytes.Equal(magic, []byte("BZh")) {
+		return &Reader{NewParser(bzip2.NewReader(br))}, nil
+	}
+	return &Reader{NewParser(br)}, nil
+}
+
+// SetUnderscoresAsSpaces sets whether underscores of unquoted names
+// are read as spaces (see Scanner.SetUnderscoresAsSpaces).
+func (r *Reader) SetUnderscoresAsSpaces(underscores bool) {
+	r.parser.SetUnderscoresAsSpaces(underscores)
+}
+
+// Next returns the next tree of the stream.
+// Returns io.EOF if there is no more tree.
+func (r *Reader) Next() (*tree.Tree, error) {
+	return r.parser.Parse()
+}
@@ -0,0 +1,90 @@
+package newick
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+)
+
+// bzip2 compression of "((A,B),C);\n(D,(E,F));\n": the standard
+// library has no bzip2 encoder
+const bzip2Trees = "\x42\x5a\x68\x39\x31\x41\x59\x26\x53\x59\x66\x34\x1d\x78\x00\x00" +
+	"\x01\x5c\x00\x00\x10\x00\x64\x00\x08\x3f\x00\x20\x00\x21\xa7\xa9" +
+	"\x34\x36\x82\x01\xa6\x9a\x29\x12\x56\x7f\xd6\xdf\x30\x4e\xd0\x8d" +
+	"\x17\x72\x45\x38\x50\x90\x66\x34\x1d\x78"
+
+func TestReader(t *testing.T) {
+	trees := "((A,B),C);\n(D,(E,F));\n"
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte(trees))
+	w.Close()
+
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{"plain", []byte(trees)},
+		{"one line", []byte("((A,B),C);(D,(E,F));")},
+		{"gzip", gz.Bytes()},
+		{"bzip2", []byte(bzip2Trees)},
+	}
+	for _, test := range tests {
+		r, err := NewReader(bytes.NewReader(test.input))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		for _, exp := range []string{"((A,B),C);", "(D,(E,F));"} {
+			tr, err := r.Next()
+			if err != nil {
+				t.Errorf("%s: %v", test.name, err)
+				break
+			}
+			if tr.Newick() != exp {
+				t.Errorf("%s: %s, expected %s", test.name, tr.Newick(), exp)
+			}
+		}
+		if _, err := r.Next(); err != io.EOF {
+			t.Errorf("%s: Next after the last tree: %v, expected io.EOF", test.name, err)
+		}
+	}
+}
+
+func TestReaderEmptyAndErrors(t *testing.T) {
+	for _, input := range []string{"", "\n \n"} {
+		r, err := NewReader(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.Next(); err != io.EOF {
+			t.Errorf("Next on %q: %v, expected io.EOF", input, err)
+		}
+	}
+
+	r, err := NewReader(strings.NewReader("ab"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Next(); err == nil || err == io.EOF {
+		t.Errorf("Next on a stream that is not Newick should return a syntax error, got %v", err)
+	}
+
+	r, err = NewReader(strings.NewReader("(A_B,C);((A,B),C;"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetUnderscoresAsSpaces(true)
+	if tr, err := r.Next(); err != nil || tr.Newick() != "('A B',C);" {
+		t.Errorf("First tree: %v (%v)", tr, err)
+	}
+	if _, err := r.Next(); err == nil || err == io.EOF {
+		t.Errorf("Malformed second tree should return a syntax error, got %v", err)
+	}
+
+	if _, err := NewReader(bytes.NewReader([]byte{0x1f, 0x8b, 0x08, 0x00})); err == nil {
+		t.Errorf("Truncated gzip header should return an error")
+	}
+}
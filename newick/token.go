@@ -17,6 +17,7 @@ const (
 	CLOSEBRACK // ] : For comment
 	NEWSIBLING // ,
 	EOT        // ;
+	QUOTED     // Quoted name of Node: 'name'
 )
 
 func isWhitespace(ch rune) bool {
@@ -0,0 +1,57 @@
+package support
+
+import (
+	"io"
+
+	"github.com/benjamincjackson/gotree/tree"
+)
+
+// Computes the classical Felsenstein bootstrap proportions of the
+// edges of the reference tree, i.e. the proportion of replicate trees,
+// read from r in Newick format, having the bipartition of each edge.
+//
+// Supports are set on the internal edges of the reference tree, with
+// values between 0 and 1. Support of terminal edges is not modified.
+// All the replicate trees must have the same tips as the reference tree.
+func Classical(reftree *tree.Tree, r io.Reader) error {
+	if err := reftree.ReinitIndexes(); err != nil {
+		return err
+	}
+	edges := reftree.Edges()
+	// Reference edges indexed by the hashcode of their bipartition
+	index := make(map[uint64][]int)
+	for i, e := range edges {
+		if !e.Trivial() {
+			index[e.HashCode()] = append(index[e.HashCode()], i)
+		}
+	}
+	counts := make([]int, len(edges))
+	// Last replicate in which each edge has been found, so that a
+	// bipartition is counted once per replicate, for every reference
+	// edge having it (e.g. both edges of the root of rooted trees)
+	lastrep := make([]int, len(edges))
+	currep := 0
+	nrep, err := forEachReplicate(reftree, r, func(rep *tree.Tree) {
+		currep++
+		for _, be := range rep.Edges() {
+			if be.Trivial() {
+				continue
+			}
+			for _, i := range index[be.HashCode()] {
+				if lastrep[i] != currep && edges[i].SameBipartition(be) {
+					counts[i]++
+					lastrep[i] = currep
+				}
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	for i, e := range edges {
+		if !e.Trivial() {
+			e.SetSupport(float64(counts[i]) / float64(nrep))
+		}
+	}
+	return nil
+}
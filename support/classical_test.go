@@ -0,0 +1,28 @@
+package support_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/benjamincjackson/gotree/support"
+	"github.com/benjamincjackson/gotree/tree"
+)
+
+func classical(ref *tree.Tree, reps string) error {
+	return support.Classical(ref, strings.NewReader(reps))
+}
+
+func TestClassical(t *testing.T) {
+	checkSupports(t, "Classical", classical, []supportTest{
+		{"((A,B),C,(D,E));", []string{"((A,B),C,(D,E));", "((A,C),B,(D,E));"},
+			map[string][]float64{"C,D,E": {0.5}, "D,E": {1}}},
+		{"((A,B),C,(D,E));", []string{"((A,C),B,(D,E));", "((A,D),C,(B,E));", "((E,D),(A,B),C);"},
+			map[string][]float64{"C,D,E": {1.0 / 3}, "D,E": {2.0 / 3}}},
+		// Both edges of a rooted reference tree have the bipartition
+		{"((A:1,B:1):1,(C:1,(D:1,E:1):1):1);", []string{"((A,B),C,(D,E));", "((A,B),C,(D,E));"},
+			map[string][]float64{"C,D,E": {1, 1}, "D,E": {1}}},
+		// The bipartition of both edges of a rooted replicate is counted once
+		{"((A,B),C,(D,E));", []string{"((A,B),(C,(D,E)));", "((A,C),B,(D,E));"},
+			map[string][]float64{"C,D,E": {0.5}, "D,E": {1}}},
+	})
+}
@@ -0,0 +1,63 @@
+/*
+Package intended to compute branch supports of a reference tree
+from a set of replicate trees (e.g. bootstrap trees):
+  - Classical Felsenstein bootstrap proportions (FBP)
+  - Transfer bootstrap expectation (TBE)
+
+Replicate trees are read one by one from a Newick stream (optionally
+gzipped or bzip2ed), so that memory usage does not depend on the number
+of replicates.
+Computed supports are stored in the edges of the reference tree.
+*/
+package support
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/benjamincjackson/gotree/newick"
+	"github.com/benjamincjackson/gotree/tree"
+)
+
+// Reads replicate trees from r one after the other, and calls f
+// on each of them, after having checked that it has the same tips
+// as the reference tree and computed its edge bitsets.
+//
+// The reference tree indexes must be up to date.
+// Returns the number of replicate trees read.
+func forEachReplicate(reftree *tree.Tree, r io.Reader, f func(rep *tree.Tree)) (nrep int, err error) {
+	reftips := reftree.SortedTips()
+	var reader *newick.Reader
+	if reader, err = newick.NewReader(r); err != nil {
+		return
+	}
+	for {
+		var rep *tree.Tree
+		if rep, err = reader.Next(); err == io.EOF {
+			err = nil
+			break
+		} else if err != nil {
+			return
+		}
+		if err = rep.ReinitIndexes(); err != nil {
+			return
+		}
+		if len(rep.Tips()) != len(reftips) {
+			err = fmt.Errorf("Replicate tree %d does not have the same number of tips as the reference tree", nrep+1)
+			return
+		}
+		for _, tip := range reftips {
+			if _, err = rep.TipIndex(tip.Name()); err != nil {
+				err = fmt.Errorf("Replicate tree %d does not have the same tips as the reference tree: %v", nrep+1, err)
+				return
+			}
+		}
+		f(rep)
+		nrep++
+	}
+	if nrep == 0 {
+		err = errors.New("No replicate tree in the input")
+	}
+	return
+}
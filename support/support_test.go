@@ -0,0 +1,95 @@
+package support_test
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/benjamincjackson/gotree/newick"
+	"github.com/benjamincjackson/gotree/tree"
+)
+
+func parse(t *testing.T, nw string) *tree.Tree {
+	t.Helper()
+	tr, err := newick.NewParser(strings.NewReader(nw)).Parse()
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", nw, err)
+	}
+	return tr
+}
+
+// Supports of the internal edges of the tree, indexed by the sorted
+// names of the tips on the side not containing A (e.g. "C,D,E" for
+// AB|CDE). Edges of the root of rooted trees are listed separately.
+func supports(tr *tree.Tree) map[string][]float64 {
+	names := tr.AllTipNames()
+	sort.Strings(names)
+	first, _ := tr.TipIndex(names[0])
+	s := make(map[string][]float64)
+	for _, e := range tr.Edges() {
+		if e.Right().Tip() {
+			continue
+		}
+		side := make([]string, 0)
+		for _, name := range names {
+			id, _ := tr.TipIndex(name)
+			if e.TipPresent(uint(id)) != e.TipPresent(uint(first)) {
+				side = append(side, name)
+			}
+		}
+		key := strings.Join(side, ",")
+		s[key] = append(s[key], e.Support())
+	}
+	return s
+}
+
+type supportTest struct {
+	ref      string
+	reps     []string
+	expected map[string][]float64
+}
+
+func checkSupports(t *testing.T, name string, compute func(*tree.Tree, string) error, tests []supportTest) {
+	t.Helper()
+	for _, test := range tests {
+		ref := parse(t, test.ref)
+		if err := compute(ref, strings.Join(test.reps, "\n")); err != nil {
+			t.Errorf("%s(%s): %v", name, test.ref, err)
+			continue
+		}
+		got := supports(ref)
+		if len(got) != len(test.expected) {
+			t.Errorf("%s(%s): %v, expected %v", name, test.ref, got, test.expected)
+			continue
+		}
+		for k, exp := range test.expected {
+			g := got[k]
+			if len(g) != len(exp) {
+				t.Errorf("%s(%s): bipartition %s: %v, expected %v", name, test.ref, k, g, exp)
+				continue
+			}
+			for i := range exp {
+				if math.Abs(g[i]-exp[i]) > 1e-9 {
+					t.Errorf("%s(%s): bipartition %s: %v, expected %v", name, test.ref, k, g, exp)
+				}
+			}
+		}
+	}
+}
+
+func TestReplicateErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"((A,B),C,(D,E));\n((A,B),C,(D,F));",
+		"((A,B),C,(D,E));\n((A,B),C,D);",
+		"((A,B),C,(D,E)",
+	}
+	for _, compute := range []func(*tree.Tree, string) error{classical, transfer} {
+		for _, reps := range tests {
+			if err := compute(parse(t, "((A,B),C,(D,E));"), reps); err == nil {
+				t.Errorf("Replicates %q should give an error", reps)
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package support
+
+import (
+	"io"
+
+	"github.com/benjamincjackson/gotree/tree"
+)
+
+// Computes the transfer bootstrap expectation (Lemoine et al., 2018)
+// of the edges of the reference tree, using the replicate trees read
+// from r in Newick format.
+//
+// For each edge b of the reference tree, with p tips on its lightest side,
+// and each replicate tree, the transfer distance d is the minimum number
+// of tips to move to obtain any bipartition of the replicate tree from
+// the bipartition of b. The transfer support of b is the mean over all
+// replicates of 1-d/(p-1).
+//
+// Supports are set on the internal edges of the reference tree, with
+// values between 0 and 1. Support of terminal edges is not modified.
+// All the replicate trees must have the same tips as the reference tree.
+func Transfer(reftree *tree.Tree, r io.Reader) error {
+	if err := reftree.ReinitIndexes(); err != nil {
+		return err
+	}
+	edges := reftree.Edges()
+	ntips := uint(len(reftree.Tips()))
+	sums := make([]float64, len(edges))
+	nrep, err := forEachReplicate(reftree, r, func(rep *tree.Tree) {
+		repedges := rep.Edges()
+		for i, e := range edges {
+			if e.Trivial() {
+				continue
+			}
+			p := e.NumTipsRight()
+			if e.NumTipsLeft() < p {
+				p = e.NumTipsLeft()
+			}
+			mindist := p - 1
+			for _, be := range repedges {
+				d := e.Bitset().SymmetricDifferenceCardinality(be.Bitset())
+				if ntips-d < d {
+					d = ntips - d
+				}
+				if int(d) < mindist {
+					mindist = int(d)
+					if mindist == 0 {
+						break
+					}
+				}
+			}
+			sums[i] += 1.0 - float64(mindist)/float64(p-1)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	for i, e := range edges {
+		if !e.Trivial() {
+			e.SetSupport(sums[i] / float64(nrep))
+		}
+	}
+	return nil
+}
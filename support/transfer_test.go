@@ -0,0 +1,25 @@
+package support_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/benjamincjackson/gotree/support"
+	"github.com/benjamincjackson/gotree/tree"
+)
+
+func transfer(ref *tree.Tree, reps string) error {
+	return support.Transfer(ref, strings.NewReader(reps))
+}
+
+func TestTransfer(t *testing.T) {
+	checkSupports(t, "Transfer", transfer, []supportTest{
+		{"((A,B),C,(D,E));", []string{"((A,B),C,(D,E));", "((A,C),B,(D,E));"},
+			map[string][]float64{"C,D,E": {0.5}, "D,E": {1}}},
+		// ABC|DEF is 1 tip away from AB|CDEF (p=3): 1-1/2
+		{"(((A,B),C),D,(E,F));", []string{"((A,B),(C,D),(E,F));"},
+			map[string][]float64{"C,D,E,F": {1}, "D,E,F": {0.5}, "E,F": {1}}},
+		{"((A:1,B:1):1,(C:1,(D:1,E:1):1):1);", []string{"((A,B),C,(D,E));", "((A,B),C,(D,E));"},
+			map[string][]float64{"C,D,E": {1, 1}, "D,E": {1}}},
+	})
+}
@@ -0,0 +1,330 @@
+package tree
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Type of the value of an annotation
+type AnnotationType int
+
+const (
+	StringAnnotation AnnotationType = iota // "UK" or UK
+	NumberAnnotation                       // 0.95
+	ListAnnotation                         // {a,b,c}
+	RangeAnnotation                        // {0.5,1.2}: list of exactly two numbers
+)
+
+// Value of an annotation
+type AnnotationValue struct {
+	Type   AnnotationType
+	Text   string            // Text of strings, and of numbers as written
+	Number float64           // Value of numbers
+	List   []AnnotationValue // Elements of lists, and bounds of ranges
+	quoted bool              // The string was written between quotes
+}
+
+// Annotation of a node or an edge: key=value
+type Annotation struct {
+	Key   string
+	Value AnnotationValue
+}
+
+// Returns a string annotation value
+func StringValue(s string) AnnotationValue {
+	return AnnotationValue{StringAnnotation, s, 0, nil, false}
+}
+
+// Returns a number annotation value
+func NumberValue(f float64) AnnotationValue {
+	return AnnotationValue{NumberAnnotation, strconv.FormatFloat(f, 'g', -1, 64), f, nil, false}
+}
+
+// Returns a list annotation value. Lists of exactly two numbers are ranges.
+func ListValue(values ...AnnotationValue) AnnotationValue {
+	v := AnnotationValue{ListAnnotation, "", 0, values, false}
+	if len(values) == 2 && values[0].Type == NumberAnnotation && values[1].Type == NumberAnnotation {
+		v.Type = RangeAnnotation
+	}
+	return v
+}
+
+// Returns a range annotation value
+func RangeValue(min, max float64) AnnotationValue {
+	return ListValue(NumberValue(min), NumberValue(max))
+}
+
+// Returns the bounds of a range value (0, 0 for other types)
+func (v AnnotationValue) Bounds() (min, max float64) {
+	if v.Type == RangeAnnotation {
+		return v.List[0].Number, v.List[1].Number
+	}
+	return 0, 0
+}
+
+// Returns the value as written in comments. Strings are quoted with
+// double quotes if they were quoted, or if they contain special characters,
+// double quotes inside the string being doubled.
+func (v AnnotationValue) String() string {
+	switch v.Type {
+	case NumberAnnotation:
+		return v.Text
+	case ListAnnotation, RangeAnnotation:
+		elts := make([]string, len(v.List))
+		for i, e := range v.List {
+			elts[i] = e.String()
+		}
+		return "{" + strings.Join(elts, ",") + "}"
+	}
+	_, err := strconv.ParseFloat(v.Text, 64)
+	if !v.quoted && v.Text != "" && err != nil && !strings.ContainsAny(v.Text, " \t\r\n,={}[]\"'") {
+		return v.Text
+	}
+	return "\"" + strings.ReplaceAll(v.Text, "\"", "\"\"") + "\""
+}
+
+// Parses a BEAST/FigTree like annotation comment, without the brackets:
+// &key=value,key2={a,b},... Values are strings (possibly quoted with
+// " or ', two consecutive quotes standing for one quote), numbers, or
+// lists of values between braces. Returns false
+// if the comment is not such an annotation comment (e.g. NHX comments,
+// starting with &&).
+func ParseAnnotations(comment string) ([]Annotation, bool) {
+	if !strings.HasPrefix(comment, "&") || strings.HasPrefix(comment, "&&") {
+		return nil, false
+	}
+	p := &annotationParser{[]rune(comment[1:]), 0}
+	annots := make([]Annotation, 0)
+	for {
+		key := strings.TrimSpace(p.until("=,{}\"'"))
+		if key == "" || !p.consume('=') {
+			return nil, false
+		}
+		v, ok := p.value()
+		if !ok {
+			return nil, false
+		}
+		annots = append(annots, Annotation{key, v})
+		p.skipSpaces()
+		if p.end() {
+			return annots, true
+		}
+		if !p.consume(',') {
+			return nil, false
+		}
+	}
+}
+
+// Formats the annotations as a comment, without the brackets
+func formatAnnotations(annots []Annotation) string {
+	entries := make([]string, len(annots))
+	for i, a := range annots {
+		entries[i] = a.Key + "=" + a.Value.String()
+	}
+	return "&" + strings.Join(entries, ",")
+}
+
+type annotationParser struct {
+	text []rune
+	i    int
+}
+
+func (p *annotationParser) end() bool {
+	return p.i >= len(p.text)
+}
+
+func (p *annotationParser) skipSpaces() {
+	for !p.end() && strings.ContainsRune(" \t\r\n", p.text[p.i]) {
+		p.i++
+	}
+}
+
+// Consumes the rune c (after spaces) if it is the next one
+func (p *annotationParser) consume(c rune) bool {
+	p.skipSpaces()
+	if !p.end() && p.text[p.i] == c {
+		p.i++
+		return true
+	}
+	return false
+}
+
+// Consumes and returns the runes until one of the stop runes
+func (p *annotationParser) until(stop string) string {
+	start := p.i
+	for !p.end() && !strings.ContainsRune(stop, p.text[p.i]) {
+		p.i++
+	}
+	return string(p.text[start:p.i])
+}
+
+func (p *annotationParser) value() (v AnnotationValue, ok bool) {
+	p.skipSpaces()
+	if p.end() {
+		return
+	}
+	switch c := p.text[p.i]; c {
+	case '{':
+		p.i++
+		values := make([]AnnotationValue, 0)
+		if p.consume('}') {
+			return ListValue(values...), true
+		}
+		for {
+			var e AnnotationValue
+			if e, ok = p.value(); !ok {
+				return
+			}
+			values = append(values, e)
+			if p.consume('}') {
+				return ListValue(values...), true
+			}
+			if !p.consume(',') {
+				return v, false
+			}
+		}
+	case '"', '\'':
+		p.i++
+		var s strings.Builder
+		for {
+			s.WriteString(p.until(string(c)))
+			if p.end() {
+				return
+			}
+			p.i++
+			// Two consecutive quotes stand for one quote
+			if p.end() || p.text[p.i] != c {
+				break
+			}
+			s.WriteRune(c)
+			p.i++
+		}
+		v = StringValue(s.String())
+		v.quoted = true
+		return v, true
+	}
+	text := strings.TrimSpace(p.until(",{}\"'"))
+	if text == "" {
+		return
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return AnnotationValue{NumberAnnotation, text, f, nil, false}, true
+	}
+	return StringValue(text), true
+}
+
+// Returns the annotations of all the annotation comments
+func commentAnnotations(comments []string) []Annotation {
+	annots := make([]Annotation, 0)
+	for _, c := range comments {
+		if a, ok := ParseAnnotations(c); ok {
+			annots = append(annots, a...)
+		}
+	}
+	return annots
+}
+
+// Returns the value of the first annotation having the key
+func commentAnnotation(comments []string, key string) (AnnotationValue, bool) {
+	for _, a := range commentAnnotations(comments) {
+		if a.Key == key {
+			return a.Value, true
+		}
+	}
+	return AnnotationValue{}, false
+}
+
+// Sets the value of the first annotation having the key, or adds the
+// annotation to the last annotation comment (to a new comment if none).
+// The other comments are not modified.
+func setCommentAnnotation(comments []string, key string, value AnnotationValue) []string {
+	last := -1
+	for i, c := range comments {
+		if annots, ok := ParseAnnotations(c); ok {
+			for j, a := range annots {
+				if a.Key == key {
+					annots[j].Value = value
+					comments[i] = formatAnnotations(annots)
+					return comments
+				}
+			}
+			last = i
+		}
+	}
+	if last == -1 {
+		return append(comments, formatAnnotations([]Annotation{{key, value}}))
+	}
+	annots, _ := ParseAnnotations(comments[last])
+	comments[last] = formatAnnotations(append(annots, Annotation{key, value}))
+	return comments
+}
+
+// Removes all the annotations having the key. Annotation comments
+// that become empty are removed.
+func deleteCommentAnnotation(comments []string, key string) []string {
+	kept := comments[:0]
+	for _, c := range comments {
+		if annots, ok := ParseAnnotations(c); ok {
+			others := make([]Annotation, 0, len(annots))
+			for _, a := range annots {
+				if a.Key != key {
+					others = append(others, a)
+				}
+			}
+			if len(others) == 0 {
+				continue
+			}
+			if len(others) != len(annots) {
+				c = formatAnnotations(others)
+			}
+		}
+		kept = append(kept, c)
+	}
+	return kept
+}
+
+// Returns the annotations of the node, parsed from its comments
+// of the form [&key=value,...], in order.
+func (n *Node) Annotations() []Annotation {
+	return commentAnnotations(n.comment)
+}
+
+// Returns the value of the annotation of the node having the key,
+// and false if there is none.
+func (n *Node) Annotation(key string) (AnnotationValue, bool) {
+	return commentAnnotation(n.comment, key)
+}
+
+// Sets the value of the annotation of the node having the key.
+// It is added if the node does not have it yet.
+func (n *Node) SetAnnotation(key string, value AnnotationValue) {
+	n.comment = setCommentAnnotation(n.comment, key, value)
+}
+
+// Removes the annotations of the node having the key.
+func (n *Node) DeleteAnnotation(key string) {
+	n.comment = deleteCommentAnnotation(n.comment, key)
+}
+
+// Returns the annotations of the edge, parsed from its comments
+// of the form [&key=value,...], in order.
+func (e *Edge) Annotations() []Annotation {
+	return commentAnnotations(e.comment)
+}
+
+// Returns the value of the annotation of the edge having the key,
+// and false if there is none.
+func (e *Edge) Annotation(key string) (AnnotationValue, bool) {
+	return commentAnnotation(e.comment, key)
+}
+
+// Sets the value of the annotation of the edge having the key.
+// It is added if the edge does not have it yet.
+func (e *Edge) SetAnnotation(key string, value AnnotationValue) {
+	e.comment = setCommentAnnotation(e.comment, key, value)
+}
+
+// Removes the annotations of the edge having the key.
+func (e *Edge) DeleteAnnotation(key string) {
+	e.comment = deleteCommentAnnotation(e.comment, key)
+}
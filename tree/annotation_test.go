@@ -0,0 +1,206 @@
+package tree_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/benjamincjackson/gotree/tree"
+)
+
+func TestParseAnnotations(t *testing.T) {
+	tests := []struct {
+		comment  string
+		expected []tree.Annotation // nil if not an annotation comment
+	}{
+		{"&country=UK", []tree.Annotation{{"country", tree.StringValue("UK")}}},
+		{"& a = 0.5 , b=-1000", []tree.Annotation{{"a", tree.NumberValue(0.5)}, {"b", tree.NumberValue(-1000)}}},
+		{"&height_range={1.5,2}", []tree.Annotation{{"height_range", tree.RangeValue(1.5, 2)}}},
+		{"&c={UK,FR}", []tree.Annotation{{"c", tree.ListValue(tree.StringValue("UK"), tree.StringValue("FR"))}}},
+		{"&c={}", []tree.Annotation{{"c", tree.ListValue()}}},
+		{"&c={a,{1,2}}", []tree.Annotation{{"c", tree.ListValue(tree.StringValue("a"), tree.RangeValue(1, 2))}}},
+		{"country=UK", nil},
+		{"&&NHX:S=human", nil},
+		{"&country", nil},
+		{"&c={a,b", nil},
+		{"&c=\"unclosed", nil},
+	}
+	for _, test := range tests {
+		annots, ok := tree.ParseAnnotations(test.comment)
+		if test.expected == nil {
+			if ok {
+				t.Errorf("ParseAnnotations(%q) = %v, expected not to be an annotation", test.comment, annots)
+			}
+			continue
+		}
+		if !ok || len(annots) != len(test.expected) {
+			t.Errorf("ParseAnnotations(%q) = %v, %v, expected %v", test.comment, annots, ok, test.expected)
+			continue
+		}
+		for i, a := range annots {
+			if a.Key != test.expected[i].Key || a.Value.String() != test.expected[i].Value.String() || a.Value.Type != test.expected[i].Value.Type {
+				t.Errorf("ParseAnnotations(%q)[%d] = %s=%s (%v), expected %s=%s (%v)", test.comment, i,
+					a.Key, a.Value, a.Value.Type, test.expected[i].Key, test.expected[i].Value, test.expected[i].Value.Type)
+			}
+		}
+	}
+}
+
+func TestAnnotationQuotes(t *testing.T) {
+	tests := []struct {
+		comment string
+		text    string
+		written string
+	}{
+		{`&n="UK"`, "UK", `"UK"`},
+		{`&n='UK'`, "UK", `"UK"`},
+		{`&n="a b,c"`, "a b,c", `"a b,c"`},
+		{`&n="say ""hi"""`, `say "hi"`, `"say ""hi"""`},
+		{`&n='O''Brien'`, "O'Brien", `"O'Brien"`},
+		{`&n='both " and '''`, `both " and '`, `"both "" and '"`},
+		{`&n=""`, "", `""`},
+		{`&n="1.5"`, "1.5", `"1.5"`},
+	}
+	for _, test := range tests {
+		annots, ok := tree.ParseAnnotations(test.comment)
+		if !ok || len(annots) != 1 {
+			t.Errorf("ParseAnnotations(%q) = %v, %v", test.comment, annots, ok)
+			continue
+		}
+		v := annots[0].Value
+		if v.Type != tree.StringAnnotation || v.Text != test.text {
+			t.Errorf("ParseAnnotations(%q): %q (%v), expected %q", test.comment, v.Text, v.Type, test.text)
+		}
+		if v.String() != test.written {
+			t.Errorf("ParseAnnotations(%q) written %s, expected %s", test.comment, v.String(), test.written)
+		}
+		// The written value is read back identically
+		back, ok := tree.ParseAnnotations("&n=" + v.String())
+		if !ok || back[0].Value.Text != test.text {
+			t.Errorf("%s read back as %v", v.String(), back)
+		}
+	}
+	if s := tree.StringValue(`"a'`).String(); s != `"""a'"` {
+		t.Errorf("StringValue with quotes written %s", s)
+	}
+}
+
+func TestNodeAnnotations(t *testing.T) {
+	tr := parse(t, "((A[&country=UK,age=3][note],B)[&posterior=0.9],C);")
+	var a, ab *tree.Node
+	for _, n := range tr.Nodes() {
+		if n.Name() == "A" {
+			a = n
+		} else if len(n.GetComments()) == 1 && !n.Tip() {
+			ab = n
+		}
+	}
+	if v, ok := a.Annotation("age"); !ok || v.Type != tree.NumberAnnotation || v.Number != 3 {
+		t.Errorf("Annotation age of A: %v %v", v, ok)
+	}
+	if _, ok := a.Annotation("note"); ok {
+		t.Errorf("Plain comments are not annotations")
+	}
+	a.SetAnnotation("country", tree.StringValue("FR"))
+	a.SetAnnotation("lineage", tree.StringValue("B.1.1"))
+	a.DeleteAnnotation("age")
+	if exp := []string{"&country=FR,lineage=B.1.1", "note"}; !reflect.DeepEqual(a.GetComments(), exp) {
+		t.Errorf("Comments of A: %q, expected %q", a.GetComments(), exp)
+	}
+	a.DeleteAnnotation("country")
+	a.DeleteAnnotation("lineage")
+	if exp := []string{"note"}; !reflect.DeepEqual(a.GetComments(), exp) {
+		t.Errorf("Comments of A: %q, expected %q", a.GetComments(), exp)
+	}
+	// NHX comments are kept as is
+	ab.DeleteAnnotation("posterior")
+	ab.AddComment("&&NHX:S=human")
+	ab.AddComment("&posterior=0.9")
+	ab.SetAnnotation("height", tree.RangeValue(1, 2))
+	if exp := []string{"&&NHX:S=human", "&posterior=0.9,height={1,2}"}; !reflect.DeepEqual(ab.GetComments(), exp) {
+		t.Errorf("Comments of AB: %q, expected %q", ab.GetComments(), exp)
+	}
+	if annots := ab.Annotations(); len(annots) != 2 || annots[1].Key != "height" {
+		t.Errorf("Annotations of AB: %v", annots)
+	}
+}
+
+func TestAnnotationsNewick(t *testing.T) {
+	tests := []struct {
+		nw       string
+		newick   string // Written with Newick
+		optional string // Written with NewickOptionalComments(true, true)
+	}{
+		// k=v comments are merged into annotations, as lists on edges
+		{"((A[x=1],B)[y=2]:1[NUC=A3G][NUC=C5T],C);",
+			"((A[x=1],B)[y=2]:1[&NUC={\"A3G\",\"C5T\"}],C);",
+			"((A[&x=1],B)[&y=2]:1[&NUC={\"A3G\",\"C5T\"}],C);"},
+		// Annotation comments are merged, in place of the first one
+		{"((A,B)[&a=1][b=2][&c={x,y}]:1[u][&s=\"O'B\"][&t='a\"b'],C);",
+			"((A,B)[&a=1][b=2][&c={x,y}]:1[u][&s=\"O'B\",t=\"a\"\"b\"],C);",
+			"((A,B)[&a=1,b=2,c={x,y}]:1[u][&s=\"O'B\",t=\"a\"\"b\"],C);"},
+		// Comments without a valid key=value are written as is
+		{"((A[=1][no value],B)[a,b=1]:1[x=1][bad],C);",
+			"((A[=1][no value],B)[a,b=1]:1[&x={\"1\"}][bad],C);",
+			"((A[=1][no value],B)[a,b=1]:1[&x={\"1\"}][bad],C);"},
+		// Brackets in quoted values do not end the comment
+		{"((A[&note=\"see [1]\"],B):1[&l='a]''b'],C);",
+			"((A[&note=\"see [1]\"],B):1[&l=\"a]'b\"],C);",
+			"((A[&note=\"see [1]\"],B):1[&l=\"a]'b\"],C);"},
+	}
+	for _, test := range tests {
+		tr := parse(t, test.nw)
+		if got := tr.Newick(); got != test.newick {
+			t.Errorf("Newick(%s) = %s, expected %s", test.nw, got, test.newick)
+		}
+		if got := tr.NewickOptionalComments(true, true); got != test.optional {
+			t.Errorf("NewickOptionalComments(%s) = %s, expected %s", test.nw, got, test.optional)
+		}
+		// Annotations are kept through a round trip
+		for _, written := range []string{tr.Newick(), tr.NewickOptionalComments(true, true)} {
+			back := parse(t, written)
+			if back.NewickOptionalComments(true, true) != test.optional {
+				t.Errorf("Round trip of %s: %s, expected %s", written, back.NewickOptionalComments(true, true), test.optional)
+			}
+		}
+	}
+}
+
+func TestAnnotationsNewickNHX(t *testing.T) {
+	// NHX comments are neither merged with annotations nor modified
+	tr := parse(t, "((A[&a=1],B):1[&b=2],C);")
+	for _, n := range tr.Tips() {
+		if n.Name() == "A" {
+			n.AddComment("&&NHX:S=h")
+			n.AddComment("&c=3")
+			n.Edges()[0].AddComment("&&NHX:B=90")
+		}
+	}
+	if exp := "((A[&a=1,c=3][&&NHX:S=h][&&NHX:B=90],B):1[&b=2],C);"; tr.NewickOptionalComments(true, true) != exp {
+		t.Errorf("NewickOptionalComments with NHX comments: %s, expected %s", tr.NewickOptionalComments(true, true), exp)
+	}
+	if exp := "((A[&a=1][&&NHX:S=h][&c=3][&&NHX:B=90],B):1[&b=2],C);"; tr.Newick() != exp {
+		t.Errorf("Newick with NHX comments: %s, expected %s", tr.Newick(), exp)
+	}
+}
+
+func TestAnnotationBracketRoundTrip(t *testing.T) {
+	tr := parse(t, "((A,B),C);")
+	a := tr.Tips()[0]
+	a.SetAnnotation("note", tree.StringValue("see [1]"))
+	a.Edges()[0].SetAnnotation("ref", tree.StringValue("]'["))
+	for _, written := range []string{tr.Newick(), tr.NewickOptionalComments(true, true), tr.NewickNHX()} {
+		back := parse(t, written)
+		b := back.Tips()[0]
+		if v, ok := b.Annotation("note"); !ok || v.Text != "see [1]" {
+			t.Errorf("Round trip of %s: note = %v, expected see [1]", written, v)
+		}
+		// NHX merges the annotations of the edge into the tags of the node
+		v, ok := b.Edges()[0].Annotation("ref")
+		if !ok {
+			v, ok = b.Annotation("ref")
+		}
+		if !ok || v.Text != "]'[" {
+			t.Errorf("Round trip of %s: ref = %v, expected ]'[", written, v)
+		}
+	}
+}
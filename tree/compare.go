@@ -0,0 +1,224 @@
+package tree
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+
+	fbitset "github.com/fredericlemoine/bitset"
+)
+
+// A bipartition of the tips of a tree, possibly restricted
+// to a subset of its tips
+type split struct {
+	bitset    *fbitset.BitSet // canonical bitset: does not contain the first tip
+	length    float64         // sum of the lengths of the edges defining it
+	haslength bool            // at least one of the edges has a length
+	trivial   bool            // one side has only one tip
+}
+
+// Computes the Robinson-Foulds distance between two trees, i.e.
+// the number of non trivial bipartitions that are present in only
+// one of the two trees.
+//
+// If sharedTipsOnly is true, both trees are first restricted to the
+// tips they have in common (the trees themselves are not modified).
+// Otherwise, an error is returned if the trees do not have the same tips.
+//
+// The tip indexes and the edge bitsets of both trees are recomputed.
+func RobinsonFoulds(t1, t2 *Tree, sharedTipsOnly bool) (int, error) {
+	s1, s2, err := compareSplits(t1, t2, sharedTipsOnly, false)
+	if err != nil {
+		return 0, err
+	}
+	rf := 0
+	for k, s := range s1 {
+		if _, ok := s2[k]; !ok && !s.trivial {
+			rf++
+		}
+	}
+	for k, s := range s2 {
+		if _, ok := s1[k]; !ok && !s.trivial {
+			rf++
+		}
+	}
+	return rf, nil
+}
+
+// Computes the Robinson-Foulds distance between two trees, divided
+// by the total number of non trivial bipartitions of both trees.
+// The result is between 0 (same topologies) and 1 (no common bipartition).
+//
+// See RobinsonFoulds for the meaning of sharedTipsOnly.
+func NormalizedRobinsonFoulds(t1, t2 *Tree, sharedTipsOnly bool) (float64, error) {
+	s1, s2, err := compareSplits(t1, t2, sharedTipsOnly, false)
+	if err != nil {
+		return 0, err
+	}
+	rf, total := 0, 0
+	for k, s := range s1 {
+		if !s.trivial {
+			total++
+			if _, ok := s2[k]; !ok {
+				rf++
+			}
+		}
+	}
+	for k, s := range s2 {
+		if !s.trivial {
+			total++
+			if _, ok := s1[k]; !ok {
+				rf++
+			}
+		}
+	}
+	if total == 0 {
+		return 0, nil
+	}
+	return float64(rf) / float64(total), nil
+}
+
+// Computes the branch score distance (Kuhner & Felsenstein, 1994)
+// between two trees: the square root of the sum, over all bipartitions
+// (including trivial ones), of the squared differences of the branch
+// lengths in both trees. A bipartition absent from a tree has a length of 0.
+//
+// Returns an error if any branch has no length.
+// See RobinsonFoulds for the meaning of sharedTipsOnly.
+func BranchScore(t1, t2 *Tree, sharedTipsOnly bool) (float64, error) {
+	s1, s2, err := compareSplits(t1, t2, sharedTipsOnly, true)
+	if err != nil {
+		return 0, err
+	}
+	sum := 0.0
+	for k, s := range s1 {
+		l2 := 0.0
+		if o, ok := s2[k]; ok {
+			l2 = o.length
+		}
+		sum += (s.length - l2) * (s.length - l2)
+	}
+	for k, s := range s2 {
+		if _, ok := s1[k]; !ok {
+			sum += s.length * s.length
+		}
+	}
+	return math.Sqrt(sum), nil
+}
+
+// Computes the weighted Robinson-Foulds distance (Robinson & Foulds, 1979)
+// between two trees: the sum, over all bipartitions (including trivial ones),
+// of the absolute differences of the branch lengths in both trees.
+// A bipartition absent from a tree has a length of 0.
+//
+// Returns an error if any branch has no length.
+// See RobinsonFoulds for the meaning of sharedTipsOnly.
+func WeightedRobinsonFoulds(t1, t2 *Tree, sharedTipsOnly bool) (float64, error) {
+	s1, s2, err := compareSplits(t1, t2, sharedTipsOnly, true)
+	if err != nil {
+		return 0, err
+	}
+	sum := 0.0
+	for k, s := range s1 {
+		l2 := 0.0
+		if o, ok := s2[k]; ok {
+			l2 = o.length
+		}
+		sum += math.Abs(s.length - l2)
+	}
+	for k, s := range s2 {
+		if _, ok := s1[k]; !ok {
+			sum += s.length
+		}
+	}
+	return sum, nil
+}
+
+// Recomputes the indexes of both trees, and returns their bipartitions
+// restricted to the tips they have in common, indexed by their key.
+func compareSplits(t1, t2 *Tree, sharedTipsOnly, needLengths bool) (s1, s2 map[string]*split, err error) {
+	if err = t1.ReinitIndexes(); err != nil {
+		return
+	}
+	if err = t2.ReinitIndexes(); err != nil {
+		return
+	}
+	common := make([]string, 0, len(t1.tipIndex))
+	for name := range t1.tipIndex {
+		if _, ok := t2.tipIndex[name]; ok {
+			common = append(common, name)
+		}
+	}
+	if !sharedTipsOnly && (len(common) != len(t1.tipIndex) || len(common) != len(t2.tipIndex)) {
+		err = errors.New("Trees do not have the same tips")
+		return
+	}
+	if len(common) < 2 {
+		err = errors.New("Trees have less than 2 tips in common")
+		return
+	}
+	sort.Strings(common)
+	if s1, err = t1.restrictedSplits(common, needLengths); err != nil {
+		return
+	}
+	s2, err = t2.restrictedSplits(common, needLengths)
+	return
+}
+
+// Returns the bipartitions defined by the edges of the tree, restricted
+// to the given sorted tip names. Edges defining the same restricted
+// bipartition (e.g. both edges of a rooted tree root, or edges collapsed
+// by the restriction) are merged and their lengths summed.
+//
+// The edge bitsets must be up to date.
+func (t *Tree) restrictedSplits(names []string, needLengths bool) (map[string]*split, error) {
+	ntips := uint(len(names))
+	// Tip index in the tree => index in the restricted bitsets
+	index := make(map[uint]uint, len(names))
+	for i, name := range names {
+		index[uint(t.tipIndex[name].tipid)] = uint(i)
+	}
+	splits := make(map[string]*split)
+	for _, e := range t.Edges() {
+		if needLengths && e.length == NIL_LENGTH {
+			return nil, errors.New("Cannot compare branch lengths: some branches have no length")
+		}
+		bs := fbitset.New(ntips)
+		for i, ok := e.bitset.NextSet(0); ok; i, ok = e.bitset.NextSet(i + 1) {
+			if j, ok := index[i]; ok {
+				bs.Set(j)
+			}
+		}
+		count := bs.Count()
+		// The edge is not a bipartition of the restricted tips
+		if count == 0 || count == ntips {
+			continue
+		}
+		if bs.Test(0) {
+			bs = bs.Complement()
+		}
+		key := bitsetKey(bs)
+		s, ok := splits[key]
+		if !ok {
+			s = &split{bs, 0.0, false, count == 1 || count == ntips-1}
+			splits[key] = s
+		}
+		if e.length != NIL_LENGTH {
+			s.length += e.length
+			s.haslength = true
+		}
+	}
+	return splits, nil
+}
+
+// Returns a string that can be used as a map key, and that
+// is the same for equal bitsets
+func bitsetKey(bs *fbitset.BitSet) string {
+	words := bs.Bytes()
+	b := make([]byte, 8*len(words))
+	for i, w := range words {
+		binary.LittleEndian.PutUint64(b[8*i:], w)
+	}
+	return string(b)
+}
@@ -0,0 +1,81 @@
+package tree_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/benjamincjackson/gotree/tree"
+)
+
+func TestRobinsonFoulds(t *testing.T) {
+	tests := []struct {
+		t1, t2     string
+		shared     bool
+		rf         int
+		normalized float64
+		err        bool
+	}{
+		{"((A,B),C,(D,E));", "((A,B),C,(D,E));", false, 0, 0, false},
+		{"((A,B),C,(D,E));", "((B,A),(E,D),C);", false, 0, 0, false},
+		{"((A,B),C,(D,E));", "((A,C),B,(D,E));", false, 2, 0.5, false},
+		{"((A,B),C,(D,E));", "((A,C),D,(B,E));", false, 4, 1, false},
+		// Both edges of the root define the same bipartition
+		{"((A,B),(C,(D,E)));", "((A,B),C,(D,E));", false, 0, 0, false},
+		{"((A,B),C,(D,E));", "((A,B),(C,F),(D,E));", true, 0, 0, false},
+		{"((A,B),C,(D,E));", "((A,C),(B,F),(D,E));", true, 2, 0.5, false},
+		{"((A,B),C,(D,E));", "((A,B),(C,F),(D,E));", false, 0, 0, true},
+		{"((A,B),C,(D,E));", "((F,G),H);", true, 0, 0, true},
+	}
+	for _, test := range tests {
+		rf, err := tree.RobinsonFoulds(parse(t, test.t1), parse(t, test.t2), test.shared)
+		if (err != nil) != test.err {
+			t.Errorf("RobinsonFoulds(%s, %s): error %v", test.t1, test.t2, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if rf != test.rf {
+			t.Errorf("RobinsonFoulds(%s, %s) = %d, expected %d", test.t1, test.t2, rf, test.rf)
+		}
+		n, _ := tree.NormalizedRobinsonFoulds(parse(t, test.t1), parse(t, test.t2), test.shared)
+		if math.Abs(n-test.normalized) > 1e-9 {
+			t.Errorf("NormalizedRobinsonFoulds(%s, %s) = %f, expected %f", test.t1, test.t2, n, test.normalized)
+		}
+	}
+}
+
+func TestBranchLengthDistances(t *testing.T) {
+	tests := []struct {
+		t1, t2 string
+		bs     float64
+		wrf    float64
+		err    bool
+	}{
+		{"((A:1,B:1):1,C:1,(D:1,E:1):1);", "((A:1,B:1):1,C:1,(D:1,E:1):1);", 0, 0, false},
+		// B differs by 1, DE by 2
+		{"((A:1,B:1):1,C:1,(D:1,E:1):1);", "((A:1,B:2):1,C:1,(D:1,E:1):3);", math.Sqrt(5), 3, false},
+		// AB (1) is absent from t2, AC (2) is absent from t1
+		{"((A:1,B:1):1,C:1,(D:1,E:1):1);", "((A:1,C:1):2,B:1,(D:1,E:1):1);", math.Sqrt(5), 3, false},
+		// The root edges are summed
+		{"((A:1,B:1):0.5,(C:1,(D:1,E:1):1):0.5);", "((A:1,B:1):1,C:1,(D:1,E:1):1);", 0, 0, false},
+		{"((A,B):1,C:1,(D:1,E:1):1);", "((A:1,B:1):1,C:1,(D:1,E:1):1);", 0, 0, true},
+	}
+	for _, test := range tests {
+		bs, err := tree.BranchScore(parse(t, test.t1), parse(t, test.t2), false)
+		if (err != nil) != test.err {
+			t.Errorf("BranchScore(%s, %s): error %v", test.t1, test.t2, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if math.Abs(bs-test.bs) > 1e-9 {
+			t.Errorf("BranchScore(%s, %s) = %f, expected %f", test.t1, test.t2, bs, test.bs)
+		}
+		wrf, _ := tree.WeightedRobinsonFoulds(parse(t, test.t1), parse(t, test.t2), false)
+		if math.Abs(wrf-test.wrf) > 1e-9 {
+			t.Errorf("WeightedRobinsonFoulds(%s, %s) = %f, expected %f", test.t1, test.t2, wrf, test.wrf)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package tree
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	fbitset "github.com/fredericlemoine/bitset"
+)
+
+// A bipartition counted over a set of trees
+type consensusSplit struct {
+	bitset  *fbitset.BitSet // canonical bitset: does not contain the first tip
+	key     string          // key of the bitset in the counting map
+	ntips   uint            // number of tips in the bitset
+	count   int             // number of trees having the bipartition
+	sumlen  float64         // sum of the branch lengths
+	nlen    int             // number of trees having a length for the bipartition
+	trivial bool            // one side has only one tip
+}
+
+// Builds the consensus of the given trees, which must all have
+// the same tips. Only the bipartitions present in a proportion
+// of the trees strictly greater than cutoff are kept:
+//   - cutoff=0.5 gives the majority-rule consensus
+//   - cutoff=1 gives the strict consensus (bipartitions present in all trees)
+//
+// The support of each edge of the consensus tree is set to the frequency of
+// its bipartition, and its length to the mean length of the bipartition in
+// the trees that have it.
+//
+// Returns an error if cutoff is lower than 0.5, as the kept bipartitions
+// may then be incompatible: GreedyConsensus should be used instead.
+//
+// The tip indexes and the edge bitsets of all the trees are recomputed.
+func Consensus(trees []*Tree, cutoff float64) (*Tree, error) {
+	if cutoff < 0.5 {
+		return nil, errors.New("Consensus cutoff must be at least 0.5, use a greedy consensus for lower cutoffs")
+	}
+	splits, tips, err := countSplits(trees)
+	if err != nil {
+		return nil, err
+	}
+	kept := make([]*consensusSplit, 0)
+	for _, s := range splits {
+		freq := float64(s.count) / float64(len(trees))
+		if !s.trivial && (freq > cutoff || s.count == len(trees)) {
+			kept = append(kept, s)
+		}
+	}
+	return buildConsensus(tips, splits, kept, len(trees))
+}
+
+// Builds the greedy consensus of the given trees, which must all
+// have the same tips. Bipartitions are considered by decreasing
+// frequency, and added to the consensus tree if they are compatible
+// with all the bipartitions already added. Bipartitions present in
+// a proportion of the trees lower than or equal to cutoff are never added,
+// so that cutoff=0 gives the fully resolved greedy consensus.
+//
+// Supports and lengths are set as in Consensus.
+func GreedyConsensus(trees []*Tree, cutoff float64) (*Tree, error) {
+	splits, tips, err := countSplits(trees)
+	if err != nil {
+		return nil, err
+	}
+	candidates := make([]*consensusSplit, 0)
+	for _, s := range splits {
+		if !s.trivial && float64(s.count)/float64(len(trees)) > cutoff {
+			candidates = append(candidates, s)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].count != candidates[j].count {
+			return candidates[i].count > candidates[j].count
+		}
+		return strings.Compare(candidates[i].key, candidates[j].key) < 0
+	})
+	kept := make([]*consensusSplit, 0)
+	for _, c := range candidates {
+		compatible := true
+		for _, k := range kept {
+			inter := c.bitset.IntersectionCardinality(k.bitset)
+			if inter != 0 && inter != c.ntips && inter != k.ntips {
+				compatible = false
+				break
+			}
+		}
+		if compatible {
+			kept = append(kept, c)
+		}
+	}
+	return buildConsensus(tips, splits, kept, len(trees))
+}
+
+// Counts the bipartitions of all the trees, including trivial ones.
+// Returns them along with the sorted tip names.
+func countSplits(trees []*Tree) (splits map[string]*consensusSplit, tips []string, err error) {
+	if len(trees) == 0 {
+		err = errors.New("No tree to build a consensus from")
+		return
+	}
+	splits = make(map[string]*consensusSplit)
+	for i, t := range trees {
+		if err = t.ReinitIndexes(); err != nil {
+			return
+		}
+		if i == 0 {
+			tips = make([]string, 0, len(t.tipIndex))
+			for name := range t.tipIndex {
+				tips = append(tips, name)
+			}
+			sort.Strings(tips)
+		} else if len(t.tipIndex) != len(tips) {
+			err = errors.New("Trees do not have the same tips")
+			return
+		}
+		for _, name := range tips {
+			if _, ok := t.tipIndex[name]; !ok {
+				err = errors.New("Trees do not have the same tips: " + name + " is missing")
+				return
+			}
+		}
+		var treesplits map[string]*split
+		if treesplits, err = t.restrictedSplits(tips, false); err != nil {
+			return
+		}
+		for key, ts := range treesplits {
+			s, ok := splits[key]
+			if !ok {
+				s = &consensusSplit{ts.bitset, key, ts.bitset.Count(), 0, 0.0, 0, ts.trivial}
+				splits[key] = s
+			}
+			s.count++
+			if ts.haslength {
+				s.sumlen += ts.length
+				s.nlen++
+			}
+		}
+	}
+	return
+}
+
+// Builds the consensus tree from the kept non trivial bipartitions,
+// that must be compatible. Terminal branch lengths are taken from the
+// trivial bipartitions of all the splits.
+func buildConsensus(tips []string, splits map[string]*consensusSplit, kept []*consensusSplit, ntrees int) (*Tree, error) {
+	// Largest bipartitions first, so that the parent of a bipartition
+	// is the closest one before it that contains it
+	sort.Slice(kept, func(i, j int) bool {
+		if kept[i].ntips != kept[j].ntips {
+			return kept[i].ntips > kept[j].ntips
+		}
+		return strings.Compare(kept[i].key, kept[j].key) < 0
+	})
+
+	t := NewTree()
+	root := t.NewNode()
+	t.SetRoot(root)
+	nodes := make([]*Node, len(kept))
+	for i, s := range kept {
+		parent := root
+		for j := i - 1; j >= 0; j-- {
+			if kept[j].bitset.IsSuperSet(s.bitset) {
+				parent = nodes[j]
+				break
+			}
+		}
+		nodes[i] = t.NewNode()
+		e := t.ConnectNodes(parent, nodes[i])
+		e.SetSupport(float64(s.count) / float64(ntrees))
+		if s.nlen > 0 {
+			e.SetLength(s.sumlen / float64(s.nlen))
+		}
+	}
+
+	ntips := uint(len(tips))
+	for i, name := range tips {
+		parent := root
+		for j := len(kept) - 1; j >= 0; j-- {
+			if kept[j].bitset.Test(uint(i)) {
+				parent = nodes[j]
+				break
+			}
+		}
+		tip := t.NewNode()
+		tip.SetName(name)
+		e := t.ConnectNodes(parent, tip)
+		// Key of the trivial bipartition of the tip
+		bs := fbitset.New(ntips)
+		bs.Set(uint(i))
+		if bs.Test(0) {
+			bs = bs.Complement()
+		}
+		if s, ok := splits[bitsetKey(bs)]; ok && s.nlen > 0 {
+			e.SetLength(s.sumlen / float64(s.nlen))
+		}
+	}
+
+	for i, n := range t.Nodes() {
+		n.SetId(i)
+	}
+	for i, e := range t.Edges() {
+		e.SetId(i)
+	}
+	if err := t.ReinitIndexes(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
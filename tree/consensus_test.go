@@ -0,0 +1,122 @@
+package tree_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/benjamincjackson/gotree/tree"
+)
+
+// Support and length of the non trivial bipartitions of the tree,
+// indexed by the side not containing A (e.g. "C,D,E" for AB|CDE)
+func internalEdges(tr *tree.Tree) map[string][2]float64 {
+	edges := make(map[string][2]float64)
+	for _, e := range tr.Edges() {
+		if !e.Right().Tip() {
+			edges[bipartition(tr, e)] = [2]float64{e.Support(), e.Length()}
+		}
+	}
+	return edges
+}
+
+func parseAll(t *testing.T, nws ...string) []*tree.Tree {
+	trees := make([]*tree.Tree, len(nws))
+	for i, nw := range nws {
+		trees[i] = parse(t, nw)
+	}
+	return trees
+}
+
+func checkEdges(t *testing.T, name string, tr *tree.Tree, expected map[string][2]float64) {
+	t.Helper()
+	got := internalEdges(tr)
+	if len(got) != len(expected) {
+		t.Errorf("%s: %s has %d internal edges, expected %d", name, tr.Newick(), len(got), len(expected))
+	}
+	for b, exp := range expected {
+		v, ok := got[b]
+		if !ok {
+			t.Errorf("%s: %s does not have bipartition %s", name, tr.Newick(), b)
+			continue
+		}
+		if math.Abs(v[0]-exp[0]) > 1e-9 || (exp[1] != tree.NIL_LENGTH && math.Abs(v[1]-exp[1]) > 1e-9) {
+			t.Errorf("%s: bipartition %s has support %f and length %f, expected %f and %f",
+				name, b, v[0], v[1], exp[0], exp[1])
+		}
+	}
+}
+
+func TestConsensus(t *testing.T) {
+	nws := []string{"((A,B),C,(D,E));", "((A,B),D,(C,E));", "((A,C),B,(D,E));"}
+	tests := []struct {
+		cutoff   float64
+		expected map[string][2]float64
+	}{
+		{0.5, map[string][2]float64{"C,D,E": {2.0 / 3, tree.NIL_LENGTH}, "D,E": {2.0 / 3, tree.NIL_LENGTH}}},
+		{0.7, map[string][2]float64{}},
+		{1, map[string][2]float64{}},
+	}
+	for _, test := range tests {
+		c, err := tree.Consensus(parseAll(t, nws...), test.cutoff)
+		if err != nil {
+			t.Errorf("Consensus(%f): %v", test.cutoff, err)
+			continue
+		}
+		checkEdges(t, "Consensus", c, test.expected)
+		if len(c.Tips()) != 5 {
+			t.Errorf("Consensus(%f) has %d tips", test.cutoff, len(c.Tips()))
+		}
+	}
+}
+
+func TestConsensusLengths(t *testing.T) {
+	trees := parseAll(t, "((A:1,B:1):2,C:1,(D:1,E:1):1);", "((A:3,B:1):4,(D:1,E:1):3,C:1);")
+	c, err := tree.Consensus(trees, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkEdges(t, "Consensus", c, map[string][2]float64{"C,D,E": {1, 3}, "D,E": {1, 2}})
+	for _, tip := range c.Tips() {
+		if tip.Name() == "A" && tip.Edges()[0].Length() != 2 {
+			t.Errorf("Length of tip A is %f, expected 2", tip.Edges()[0].Length())
+		}
+	}
+}
+
+func TestConsensusErrors(t *testing.T) {
+	tests := []struct {
+		nws    []string
+		cutoff float64
+	}{
+		{[]string{}, 0.5},
+		{[]string{"((A,B),C,(D,E));", "((A,B),C,(D,F));"}, 0.5},
+		{[]string{"((A,B),C,(D,E));", "((A,B),C,D);"}, 0.5},
+		// Incompatible bipartitions would be kept
+		{[]string{"((A,B),C,(D,E));", "((A,C),B,(D,E));", "((B,C),A,(D,E));"}, 0.3},
+	}
+	for _, test := range tests {
+		if _, err := tree.Consensus(parseAll(t, test.nws...), test.cutoff); err == nil {
+			t.Errorf("Consensus(%v, %f) should return an error", test.nws, test.cutoff)
+		}
+	}
+}
+
+func TestGreedyConsensus(t *testing.T) {
+	nws := []string{"((A,B),C,(D,E));", "((A,C),B,(D,E));", "((B,C),A,(D,E));", "((A,B),C,(D,E));"}
+	tests := []struct {
+		cutoff   float64
+		expected map[string][2]float64
+	}{
+		// AB is the most frequent after DE, AC and BC are incompatible with it
+		{0, map[string][2]float64{"C,D,E": {0.5, tree.NIL_LENGTH}, "D,E": {1, tree.NIL_LENGTH}}},
+		{0.5, map[string][2]float64{"D,E": {1, tree.NIL_LENGTH}}},
+	}
+	for _, test := range tests {
+		c, err := tree.GreedyConsensus(parseAll(t, nws...), test.cutoff)
+		if err != nil {
+			t.Errorf("GreedyConsensus(%f): %v", test.cutoff, err)
+			continue
+		}
+		checkEdges(t, "GreedyConsensus", c, test.expected)
+	}
+}
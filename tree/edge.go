@@ -45,6 +45,12 @@ func (e *Edge) setRight(right *Node) {
 	e.right = right
 }
 
+// Returns the Id of the branch. Id==NIL_ID means that
+// it has not been set yet.
+func (e *Edge) Id() int {
+	return e.id
+}
+
 // Sets the id of the branch
 func (e *Edge) SetId(id int) {
 	e.id = id
@@ -106,6 +112,64 @@ func (e *Edge) SetPValue(pval float64) {
 	e.pvalue = pval
 }
 
+// Returns the bitset of the edge. Bits set to 1 are the
+// tips on the right side of the edge (below it).
+// Returns nil if the bitsets have not been computed with
+// ReinitIndexes or UpdateBitSet.
+func (e *Edge) Bitset() *fbitset.BitSet {
+	return e.bitset
+}
+
+// Returns true if the tip with the given index (in the
+// tip index of the tree) is on the right side of the edge
+func (e *Edge) TipPresent(id uint) bool {
+	return e.bitset != nil && e.bitset.Test(id)
+}
+
+// Returns the number of tips on the right side of the edge
+// (below it). Initialized by ReinitIndexes or UpdateBitSet.
+func (e *Edge) NumTipsRight() int {
+	return e.ntaxright
+}
+
+// Returns the number of tips on the left side of the edge
+// (above it). Initialized by ReinitIndexes or UpdateBitSet.
+func (e *Edge) NumTipsLeft() int {
+	return e.ntaxleft
+}
+
+// Returns true if the edge leads to a tip, or if one of
+// its sides has no more than one tip
+func (e *Edge) Trivial() bool {
+	return e.right.Tip() || e.left.Tip() || e.ntaxright <= 1 || e.ntaxleft <= 1
+}
+
+// Returns the hashcode of the bipartition defined by the edge.
+// It does not depend on the orientation of the edge, so that
+// the same bipartition in two trees having the same tips has
+// the same hashcode. Returns 0 if bitsets are not computed.
+func (e *Edge) HashCode() uint64 {
+	if e.bitset == nil {
+		return 0
+	}
+	// The side not containing the first tip is the reference
+	if e.bitset.Test(0) {
+		return e.hashcodeleft
+	}
+	return e.hashcoderight
+}
+
+// Returns true if the two edges define the same bipartition
+// of the tips, whatever their orientation. The bitsets of
+// both trees must have been computed with the same tip index,
+// i.e. the trees must have the same tips.
+func (e *Edge) SameBipartition(other *Edge) bool {
+	if e.bitset == nil || other.bitset == nil {
+		return false
+	}
+	return e.bitset.EqualOrComplement(other.bitset)
+}
+
 // Returns the node at the right side of the edge (child)
 func (e *Edge) Right() *Node {
 	return e.right
@@ -115,3 +179,48 @@ func (e *Edge) Right() *Node {
 func (e *Edge) Left() *Node {
 	return e.left
 }
+
+// Returns a copy of the edge, without its nodes
+func (e *Edge) copy() *Edge {
+	c := &Edge{
+		length:        e.length,
+		comment:       append(make([]string, 0, len(e.comment)), e.comment...),
+		support:       e.support,
+		pvalue:        e.pvalue,
+		hashcoderight: e.hashcoderight,
+		hashcodeleft:  e.hashcodeleft,
+		ntaxright:     e.ntaxright,
+		ntaxleft:      e.ntaxleft,
+		id:            e.id,
+		SynLen:        e.SynLen,
+	}
+	if e.bitset != nil {
+		c.bitset = e.bitset.Clone()
+	}
+	return c
+}
+
+// Merges the edge other into this edge, that are consecutive
+// edges separated by a node of degree 2:
+//   - lengths and SynLen are summed
+//   - the support (and pvalue) of this edge is kept if any,
+//     else the one of the other edge is taken
+//   - comments of the other edge are placed before the comments
+//     of this edge, without duplicates
+func (e *Edge) merge(other *Edge) {
+	e.length = sumLengths(other.length, e.length)
+	e.SynLen += other.SynLen
+	if e.support == NIL_SUPPORT {
+		e.support = other.support
+		e.pvalue = other.pvalue
+	}
+	comments := make([]string, 0, len(other.comment)+len(e.comment))
+	seen := make(map[string]bool, cap(comments))
+	for _, c := range append(append([]string{}, other.comment...), e.comment...) {
+		if !seen[c] {
+			comments = append(comments, c)
+			seen[c] = true
+		}
+	}
+	e.comment = comments
+}
@@ -0,0 +1,160 @@
+package tree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// One single state slice for each possible state. They are shared
+// by all the nodes whose state is resolved, and must not be modified.
+var singleStates [256][]byte
+
+func init() {
+	for i := range singleStates {
+		singleStates[i] = []byte{byte(i)}
+	}
+}
+
+// Reconstructs ancestral states with the Fitch small parsimony algorithm,
+// using the states of the tips (see SetTipStates). For each tip and each
+// site, the tip Upstates give the set of possible states (several states
+// for ambiguities). They are not modified, even if the root is a tip.
+//
+// The up-pass (post-order) sets the Upstates of the internal nodes to
+// their Fitch state sets: the states present in the largest number of
+// their children sets (i.e. the intersection of the sets of their children
+// if not empty, else their union, for binary nodes). The down-pass (pre-order) then sets the
+// Downstates of all the nodes (including tips) to a single resolved state
+// per site: the state of the parent if it is in the Upstates of the node,
+// else the lowest state of its Upstates (also used for the root).
+//
+// Returns the parsimony score (minimum number of changes) of each site.
+// State slices may be shared between nodes, and must not be modified in place.
+func (t *Tree) Fitch() (scores []int, err error) {
+	tips := t.Tips()
+	if len(tips) == 0 {
+		return nil, errors.New("No tips in the tree")
+	}
+	nsites := len(tips[0].Upstates)
+	for _, tip := range tips {
+		if len(tip.Upstates) != nsites {
+			return nil, fmt.Errorf("Tip %s has %d sites, expected %d", tip.name, len(tip.Upstates), nsites)
+		}
+		for i, s := range tip.Upstates {
+			if len(s) == 0 {
+				return nil, fmt.Errorf("Tip %s has no state at site %d", tip.name, i+1)
+			}
+		}
+	}
+	scores = make([]int, nsites)
+
+	// Up-pass
+	t.PostOrder(func(cur *Node, prev *Node, e *Edge) (keep bool) {
+		if cur.Tip() {
+			// The observed states of tips are kept. A tip being the root
+			// is the parent of its only neighbor: one change is needed
+			// when their sets do not intersect.
+			if prev == nil && len(cur.neigh) == 1 {
+				for site, set := range cur.neigh[0].Upstates {
+					if !intersects(set, cur.Upstates[site]) {
+						scores[site]++
+					}
+				}
+			}
+			return true
+		}
+		children := make([]*Node, 0, len(cur.neigh))
+		for _, n := range cur.neigh {
+			if n != prev {
+				children = append(children, n)
+			}
+		}
+		cur.Upstates = make([][]byte, nsites)
+		var counts [256]int
+		for site := 0; site < nsites; site++ {
+			// States present in the largest number of children
+			max := 0
+			for _, c := range children {
+				for _, s := range c.Upstates[site] {
+					counts[s]++
+					if counts[s] > max {
+						max = counts[s]
+					}
+				}
+			}
+			set := make([]byte, 0, 4)
+			for _, c := range children {
+				for _, s := range c.Upstates[site] {
+					if counts[s] == max && !containsState(set, s) {
+						set = append(set, s)
+					}
+					counts[s] = 0
+				}
+			}
+			scores[site] += len(children) - max
+			cur.Upstates[site] = sharedStates(set, children, site)
+		}
+		return true
+	})
+
+	// Down-pass
+	t.PreOrder(func(cur *Node, prev *Node, e *Edge) (keep bool) {
+		cur.Downstates = make([][]byte, nsites)
+		for site := 0; site < nsites; site++ {
+			set := cur.Upstates[site]
+			state := set[0]
+			for _, s := range set[1:] {
+				if s < state {
+					state = s
+				}
+			}
+			if prev != nil {
+				parent := prev.Downstates[site][0]
+				if containsState(set, parent) {
+					state = parent
+				}
+			}
+			cur.Downstates[site] = singleStates[state]
+		}
+		return true
+	})
+	return scores, nil
+}
+
+// Returns a slice equal to the given state set, sorted: the state
+// slice of one of the children at the given site if one is equal
+// to it, so that state slices are shared as much as possible.
+func sharedStates(set []byte, children []*Node, site int) []byte {
+	if len(set) == 1 {
+		return singleStates[set[0]]
+	}
+	sort.Slice(set, func(i, j int) bool { return set[i] < set[j] })
+	for _, c := range children {
+		if bytes.Equal(c.Upstates[site], set) {
+			return c.Upstates[site]
+		}
+	}
+	return set
+}
+
+// Returns true if the state set contains the state
+func containsState(set []byte, state byte) bool {
+	for _, s := range set {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if the two state sets have a state in common
+func intersects(set1, set2 []byte) bool {
+	for _, s := range set1 {
+		if containsState(set2, s) {
+			return true
+		}
+	}
+	return false
+}
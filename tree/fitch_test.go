@@ -0,0 +1,100 @@
+package tree_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/benjamincjackson/gotree/tree"
+)
+
+// States of each site, one string per site: "AG" is an ambiguity
+func states(sites ...string) [][]byte {
+	s := make([][]byte, len(sites))
+	for i, site := range sites {
+		s[i] = []byte(site)
+	}
+	return s
+}
+
+func TestFitch(t *testing.T) {
+	tr := parseIndexed(t, "((A,B)ab,C,(D,E)de)root;")
+	tips := map[string][][]byte{
+		"A": states("A", "A", "A", "AG"),
+		"B": states("A", "A", "C", "G"),
+		"C": states("C", "A", "G", "G"),
+		"D": states("C", "A", "T", "T"),
+		"E": states("C", "A", "A", "T"),
+	}
+	for name, s := range tips {
+		if err := tr.SetTipStates(name, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	scores, err := tr.Fitch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []int{1, 0, 3, 1}; !reflect.DeepEqual(scores, exp) {
+		t.Errorf("Fitch scores = %v, expected %v", scores, exp)
+	}
+	expected := map[string]struct{ up, down [][]byte }{
+		"ab":   {states("A", "A", "AC", "G"), states("A", "A", "A", "G")},
+		"de":   {states("C", "A", "AT", "T"), states("C", "A", "A", "T")},
+		"root": {states("C", "A", "A", "G"), states("C", "A", "A", "G")},
+		"A":    {states("A", "A", "A", "AG"), states("A", "A", "A", "G")},
+		"E":    {states("C", "A", "A", "T"), states("C", "A", "A", "T")},
+	}
+	for _, n := range tr.Nodes() {
+		if exp, ok := expected[n.Name()]; ok {
+			if !reflect.DeepEqual(n.Upstates, exp.up) {
+				t.Errorf("Upstates of %s = %q, expected %q", n.Name(), n.Upstates, exp.up)
+			}
+			if !reflect.DeepEqual(n.Downstates, exp.down) {
+				t.Errorf("Downstates of %s = %q, expected %q", n.Name(), n.Downstates, exp.down)
+			}
+		}
+	}
+}
+
+func TestFitchTipRoot(t *testing.T) {
+	// The root is the tip A, connected to X, parent of B and C
+	tr := tree.NewTree()
+	a, x, b, c := tr.NewNode(), tr.NewNode(), tr.NewNode(), tr.NewNode()
+	a.SetName("A")
+	b.SetName("B")
+	c.SetName("C")
+	tr.SetRoot(a)
+	tr.ConnectNodes(a, x)
+	tr.ConnectNodes(x, b)
+	tr.ConnectNodes(x, c)
+	a.SetUpstates(states("A", "C"))
+	b.SetUpstates(states("C", "C"))
+	c.SetUpstates(states("C", "G"))
+	scores, err := tr.Fitch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []int{1, 1}; !reflect.DeepEqual(scores, exp) {
+		t.Errorf("Fitch scores = %v, expected %v", scores, exp)
+	}
+	if exp := states("A", "C"); !reflect.DeepEqual(a.Upstates, exp) {
+		t.Errorf("Upstates of the root tip = %q, expected %q", a.Upstates, exp)
+	}
+	if exp := states("C", "C"); !reflect.DeepEqual(x.Downstates, exp) {
+		t.Errorf("Downstates of X = %q, expected %q", x.Downstates, exp)
+	}
+}
+
+func TestFitchErrors(t *testing.T) {
+	tr := parseIndexed(t, "((A,B),C);")
+	tr.SetTipStates("A", states("A", "C"))
+	tr.SetTipStates("B", states("A"))
+	tr.SetTipStates("C", states("A", "C"))
+	if _, err := tr.Fitch(); err == nil {
+		t.Errorf("Fitch with different numbers of sites should return an error")
+	}
+	tr.SetTipStates("B", states("A", ""))
+	if _, err := tr.Fitch(); err == nil {
+		t.Errorf("Fitch with an empty state set should return an error")
+	}
+}
@@ -2,6 +2,7 @@ package tree
 
 import (
 	"bytes"
+	"strconv"
 	"strings"
 )
 
@@ -11,9 +12,7 @@ func (t *Tree) NewickOptionalComments(annotate_nodes bool, annotate_tips bool) s
 	t.root.NewickOptionalComments(nil, &buffer, annotate_nodes, annotate_tips)
 	if len(t.root.comment) != 0 {
 		if annotate_nodes {
-			buffer.WriteString("[&")
-			buffer.WriteString(strings.Join(t.root.comment, ","))
-			buffer.WriteString("]")
+			buffer.WriteString(joinComments(t.root.comment))
 		}
 	}
 	buffer.WriteString(";")
@@ -34,3 +33,37 @@ func (t *Tree) Newick() string {
 	buffer.WriteString(";")
 	return buffer.String()
 }
+
+// Returns a NHX (New Hampshire eXtended) string representation of this
+// tree: annotations and supports are written as [&&NHX:key=value:B=support]
+// comments, that are read back by the newick parser. Edge annotations are
+// read back as annotations of the node below the edge.
+func (t *Tree) NewickNHX() string {
+	var buffer bytes.Buffer
+	t.root.NewickNHX(nil, &buffer)
+	buffer.WriteString(nhxComment(t.root, nil))
+	buffer.WriteString(rawNHXComments(t.root))
+	buffer.WriteString(";")
+	return buffer.String()
+}
+
+// Returns the name as written in Newick: quoted with single quotes if it
+// contains whitespaces, underscores (read as spaces by some parsers), quotes
+// or Newick special characters, quotes inside the name being doubled. Names
+// of internal nodes that would be read as supports (numbers) are quoted as well.
+func newickName(name string, internal bool) string {
+	quote := strings.ContainsAny(name, " \t\r\n_'()[]:;,") || strings.TrimSpace(name) != name
+	if internal && !quote {
+		_, err := strconv.ParseFloat(name, 64)
+		quote = err == nil
+		if vals := strings.Split(name, "/"); len(vals) == 2 {
+			_, err1 := strconv.ParseFloat(vals[0], 64)
+			_, err2 := strconv.ParseFloat(vals[1], 64)
+			quote = quote || (err1 == nil && err2 == nil)
+		}
+	}
+	if !quote {
+		return name
+	}
+	return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+}
@@ -0,0 +1,121 @@
+package tree
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A coding region of the alignment, used to translate codons
+// when annotating mutations
+type Region struct {
+	Name  string // Name of the region (gene), as written in AA comments
+	Start int    // 1-based position of the first nucleotide of the region in the alignment
+	End   int    // 1-based position of the last nucleotide of the region (included)
+	Frame int    // Offset (0, 1 or 2) of the first codon from Start
+}
+
+// Standard genetic code, codons being ordered by the index
+// of their nucleotides in "TCAG"
+const (
+	codonBases = "TCAG"
+	codonAAs   = "FFLLSSSSYY**CC*WLLLLPPPPHHQQRRRRIIIMTTTTNNKKSSRRVVVVAAAADDEEGGGG"
+)
+
+// Translates a codon with the standard genetic code. Returns 'X'
+// if the codon contains anything else than A, C, G or T.
+func translate(codon []byte) byte {
+	index := 0
+	for _, n := range codon {
+		i := strings.IndexByte(codonBases, n)
+		if i < 0 {
+			return 'X'
+		}
+		index = index*4 + i
+	}
+	return codonAAs[index]
+}
+
+// Annotates each edge of the tree with the mutations between the
+// Downstates of its left (parent) and right (child) nodes, that must
+// have been reconstructed before (e.g. with Fitch), one state per site.
+//
+// Nucleotide mutations are added as edge comments of the form
+//
+//	NUC=C241T
+//
+// with 1-based positions in the alignment. Amino acid mutations in
+// the given coding regions are added as edge comments of the form
+//
+//	AA=S:614:DG
+//
+// with the name of the region, the 1-based position of the codon in the
+// region, and the parental and child amino acids. The SynLen of each edge
+// is set to the number of nucleotide mutations in codons that do not
+// change the amino acid.
+//
+// Existing AA and NUC comments of the edges are replaced.
+func (t *Tree) AnnotateMutations(regions []Region) error {
+	nodes := t.Nodes()
+	if len(nodes) == 0 {
+		return fmt.Errorf("No nodes in the tree")
+	}
+	nsites := len(t.root.Downstates)
+	if nsites == 0 {
+		return fmt.Errorf("No reconstructed states: states should be reconstructed first")
+	}
+	for _, n := range nodes {
+		if len(n.Downstates) != nsites {
+			return fmt.Errorf("Node %s has %d reconstructed sites, expected %d: states should be reconstructed first", n.name, len(n.Downstates), nsites)
+		}
+	}
+	for _, r := range regions {
+		if r.Start < 1 || r.End > nsites || r.Start > r.End || r.Frame < 0 || r.Frame > 2 {
+			return fmt.Errorf("Invalid coordinates for region %s: start=%d, end=%d, frame=%d", r.Name, r.Start, r.End, r.Frame)
+		}
+	}
+	parent := make([]byte, nsites)
+	child := make([]byte, nsites)
+	for _, e := range t.Edges() {
+		comments := make([]string, 0, len(e.comment))
+		for _, c := range e.comment {
+			if !strings.HasPrefix(c, "AA=") && !strings.HasPrefix(c, "NUC=") {
+				comments = append(comments, c)
+			}
+		}
+		for i := 0; i < nsites; i++ {
+			parent[i] = e.left.Downstates[i][0]
+			child[i] = e.right.Downstates[i][0]
+			if parent[i] != child[i] {
+				comments = append(comments, "NUC="+string(parent[i])+strconv.Itoa(i+1)+string(child[i]))
+			}
+		}
+		e.SynLen = 0
+		for _, r := range regions {
+			aacounter := 1
+			// Start and End are 1-based
+			for pos := r.Start - 1 + r.Frame; pos+3 <= r.End; pos += 3 {
+				upcodon, downcodon := parent[pos:pos+3], child[pos:pos+3]
+				nchanges := 0
+				for i := range upcodon {
+					if upcodon[i] != downcodon[i] {
+						nchanges++
+					}
+				}
+				if nchanges > 0 {
+					upAA, downAA := translate(upcodon), translate(downcodon)
+					if upAA != 'X' && downAA != 'X' {
+						if upAA != downAA {
+							comments = append(comments, "AA="+r.Name+":"+strconv.Itoa(aacounter)+":"+string(upAA)+string(downAA))
+						} else {
+							e.SynLen += float64(nchanges)
+						}
+					}
+				}
+				aacounter++
+			}
+		}
+		e.comment = comments
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package tree_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/benjamincjackson/gotree/tree"
+)
+
+func TestAnnotateMutations(t *testing.T) {
+	tr := parseIndexed(t, "((A,B)ab,C);")
+	seqs := map[string]string{"A": "ATGGCT", "B": "ATGGCC", "C": "ATAGCT"}
+	for name, seq := range seqs {
+		s := make([]string, len(seq))
+		for i := range seq {
+			s[i] = seq[i : i+1]
+		}
+		tr.SetTipStates(name, states(s...))
+	}
+	if _, err := tr.Fitch(); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range tr.Edges() {
+		e.AddComment("NUC=X1Y")
+		e.AddComment("kept")
+	}
+	if err := tr.AnnotateMutations([]tree.Region{{"S", 1, 6, 0}}); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]struct {
+		comments []string
+		aas      []string
+		synlen   float64
+	}{
+		// The root has A at site 3: ATA (I) -> ATG (M)
+		"ab": {[]string{"kept", "NUC=A3G", "AA=S:1:IM"}, []string{"AA=S:1"}, 0},
+		"A":  {[]string{"kept"}, []string{}, 0},
+		// GCT -> GCC: both are alanine
+		"B": {[]string{"kept", "NUC=T6C"}, []string{}, 1},
+		"C": {[]string{"kept"}, []string{}, 0},
+	}
+	for _, e := range tr.Edges() {
+		exp := expected[e.Right().Name()]
+		if !reflect.DeepEqual(e.GetComments(), exp.comments) {
+			t.Errorf("Comments of the edge above %s = %q, expected %q", e.Right().Name(), e.GetComments(), exp.comments)
+		}
+		if aas := e.Get_AA_residues(); !reflect.DeepEqual(aas, exp.aas) {
+			t.Errorf("AA residues of the edge above %s = %q, expected %q", e.Right().Name(), aas, exp.aas)
+		}
+		if e.SynLen != exp.synlen {
+			t.Errorf("SynLen of the edge above %s = %f, expected %f", e.Right().Name(), e.SynLen, exp.synlen)
+		}
+	}
+	// Mutations are written as lists in a single annotation comment
+	if exp := `((A[kept],B[kept][&NUC={"T6C"}])ab[kept][&NUC={"A3G"},AA={"S:1:IM"}],C[kept]);`; tr.Newick() != exp {
+		t.Errorf("Newick() = %s, expected %s", tr.Newick(), exp)
+	}
+}
+
+func TestAnnotateMutationsErrors(t *testing.T) {
+	tr := parseIndexed(t, "((A,B),C);")
+	if err := tr.AnnotateMutations(nil); err == nil {
+		t.Errorf("AnnotateMutations without reconstructed states should return an error")
+	}
+	for _, name := range []string{"A", "B", "C"} {
+		tr.SetTipStates(name, states("A", "T", "G"))
+	}
+	if _, err := tr.Fitch(); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []tree.Region{{"S", 0, 3, 0}, {"S", 1, 4, 0}, {"S", 3, 1, 0}, {"S", 1, 3, 3}} {
+		if err := tr.AnnotateMutations([]tree.Region{r}); err == nil {
+			t.Errorf("AnnotateMutations with region %v should return an error", r)
+		}
+	}
+}
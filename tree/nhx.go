@@ -0,0 +1,135 @@
+package tree
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// Prefix of NHX (New Hampshire eXtended) comments: [&&NHX:S=human:D=Y:B=100]
+const nhxPrefix = "&&NHX"
+
+// Key of NHX tags giving the support of the edge above the node
+const nhxSupport = "B"
+
+// Parses a NHX comment, without the brackets: &&NHX:key=value:key2=value2...
+// Tags are separated by the ':' followed by a key and a '=', so that values
+// may contain ':' (e.g. T=12:00). Values are parsed as annotation values
+// (numbers, strings, or lists between braces), values that cannot be parsed
+// being kept as strings. Returns false if the comment is not a valid NHX comment.
+func ParseNHX(comment string) ([]Annotation, bool) {
+	if !strings.HasPrefix(comment, nhxPrefix) {
+		return nil, false
+	}
+	annots := make([]Annotation, 0)
+	for _, tag := range nhxTags(strings.TrimPrefix(comment[len(nhxPrefix):], ":")) {
+		if strings.TrimSpace(tag) == "" {
+			continue
+		}
+		kv := strings.SplitN(tag, "=", 2)
+		key := strings.TrimSpace(kv[0])
+		if len(kv) != 2 || key == "" {
+			return nil, false
+		}
+		p := &annotationParser{[]rune(kv[1]), 0}
+		v, ok := p.value()
+		if p.skipSpaces(); !ok || !p.end() {
+			v = StringValue(strings.TrimSpace(kv[1]))
+		}
+		annots = append(annots, Annotation{key, v})
+	}
+	return annots, true
+}
+
+// Splits the tags of a NHX comment at each ':' starting a key= token
+func nhxTags(tags string) []string {
+	split := make([]string, 0)
+	start := 0
+	for i := 0; i < len(tags); i++ {
+		if tags[i] != ':' {
+			continue
+		}
+		eq := strings.IndexByte(tags[i+1:], '=')
+		if eq > 0 && !strings.ContainsAny(tags[i+1:i+1+eq], ":,{}[]\"' \t\r\n") {
+			split = append(split, tags[start:i])
+			start = i + 1
+		}
+	}
+	return append(split, tags[start:])
+}
+
+// Returns the NHX comment of the node and of the edge above it (nil
+// for the root), or "" if they have neither annotations nor support.
+// Annotations of the node come first, then the annotations of the
+// edge whose keys are not already present, then the support (B).
+//
+// NHX has a single tag list per node, so node and edge annotations are
+// merged: they are all read back as annotations of the node, and edge
+// annotations having the key of a node annotation are not written.
+func nhxComment(n *Node, e *Edge) string {
+	tags := make([]string, 0)
+	keys := make(map[string]bool)
+	annots := n.Annotations()
+	if e != nil {
+		annots = append(annots, e.Annotations()...)
+	}
+	for _, a := range annots {
+		if keys[a.Key] || (e != nil && e.support != NIL_SUPPORT && a.Key == nhxSupport) {
+			continue
+		}
+		keys[a.Key] = true
+		tags = append(tags, a.Key+"="+a.Value.String())
+	}
+	if e != nil && e.support != NIL_SUPPORT {
+		tags = append(tags, nhxSupport+"="+strconv.FormatFloat(e.support, 'f', -1, 64))
+	}
+	if len(tags) == 0 {
+		return ""
+	}
+	return "[" + nhxPrefix + ":" + strings.Join(tags, ":") + "]"
+}
+
+// Returns the NHX comments of the node that could not be parsed
+// as annotations, each between [], to write them back as is
+func rawNHXComments(n *Node) string {
+	raw := ""
+	for _, c := range n.comment {
+		if strings.HasPrefix(c, nhxPrefix) {
+			raw += "[" + c + "]"
+		}
+	}
+	return raw
+}
+
+// Recursive function that outputs the NHX representation
+// from the current node: as Newick, but with the annotations of
+// the nodes and edges, and the supports, written as NHX comments
+// after the branch lengths. NHX comments that could not be parsed
+// are written as is, other comments are not written.
+func (n *Node) NewickNHX(parent *Node, newick *bytes.Buffer) {
+	if len(n.neigh) > 0 {
+		if len(n.neigh) > 1 {
+			newick.WriteString("(")
+		}
+		nbchild := 0
+		for i, child := range n.neigh {
+			if child != parent {
+				if nbchild > 0 {
+					newick.WriteString(",")
+				}
+				child.NewickNHX(n, newick)
+				if n.br[i].length != NIL_LENGTH {
+					newick.WriteString(":")
+					newick.WriteString(strconv.FormatFloat(n.br[i].length, 'f', -1, 64))
+				}
+				newick.WriteString(nhxComment(child, n.br[i]))
+				newick.WriteString(rawNHXComments(child))
+				nbchild++
+			}
+		}
+		if len(n.neigh) > 1 {
+			newick.WriteString(")")
+		}
+	}
+	newick.WriteString(newickName(n.name, len(n.neigh) > 1))
+}
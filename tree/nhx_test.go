@@ -0,0 +1,97 @@
+package tree_test
+
+import (
+	"testing"
+
+	"github.com/benjamincjackson/gotree/tree"
+)
+
+func TestParseNHX(t *testing.T) {
+	tests := []struct {
+		comment  string
+		expected string // Annotations formatted as key=value;..., "" if invalid
+	}{
+		{"&&NHX:S=human:D=Y:B=100", "S=human;D=Y;B=100"},
+		{"&&NHX:S=h:T=12:00", "S=h;T=12:00"},
+		{"&&NHX:T=12:00:00:S=h", "T=12:00:00;S=h"},
+		{"&&NHX:L={1,2}:N=\"a b\"", "L={1,2};N=\"a b\""},
+		{"&&NHX:X=a{b", "X=\"a{b\""},
+		{"&&NHX", ""},
+		{"&&NHX:", ""},
+		{"&&NHX:junk:S=h", "invalid"},
+		{"&&NHX:=1", "invalid"},
+		{"&S=h", "invalid"},
+	}
+	for _, test := range tests {
+		annots, ok := tree.ParseNHX(test.comment)
+		if test.expected == "invalid" {
+			if ok {
+				t.Errorf("ParseNHX(%q) = %v, expected to be invalid", test.comment, annots)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("ParseNHX(%q) is invalid, expected %s", test.comment, test.expected)
+			continue
+		}
+		got := ""
+		for i, a := range annots {
+			if i > 0 {
+				got += ";"
+			}
+			got += a.Key + "=" + a.Value.String()
+		}
+		if got != test.expected {
+			t.Errorf("ParseNHX(%q) = %s, expected %s", test.comment, got, test.expected)
+		}
+	}
+}
+
+func TestNewickNHX(t *testing.T) {
+	tests := []struct {
+		nw       string
+		expected string
+	}{
+		{"((A:1[&&NHX:S=human],B:2)0.9:1,C:3)[&&NHX:D=N];",
+			"((A:1[&&NHX:S=human],B:2):1[&&NHX:B=0.9],C:3)[&&NHX:D=N];"},
+		// B is the support of the edge above the node
+		{"((A,B)[&&NHX:B=80:S=x],C);", "((A,B)[&&NHX:S=x:B=80],C);"},
+		{"((A[&&NHX:S=h:T=12:00]:1,B:2):1,C);", "((A:1[&&NHX:S=h:T=12:00],B:2):1,C);"},
+		// Annotation comments are written as NHX tags, other comments are not
+		{"((A[&c=UK][note]:1,B):1[&x={1,2}],C);", "((A:1[&&NHX:c=UK],B):1[&&NHX:x={1,2}],C);"},
+		// Comments that are not valid NHX are kept as is
+		{"((A[&&NHX:junk],B),C);", "((A[&&NHX:junk],B),C);"},
+	}
+	for _, test := range tests {
+		tr := parse(t, test.nw)
+		if got := tr.NewickNHX(); got != test.expected {
+			t.Errorf("NewickNHX(%s) = %s, expected %s", test.nw, got, test.expected)
+			continue
+		}
+		// Round trip
+		if back := parse(t, test.expected).NewickNHX(); back != test.expected {
+			t.Errorf("NHX round trip of %s: %s", test.expected, back)
+		}
+	}
+}
+
+func TestNewickNHXEdgeAnnotations(t *testing.T) {
+	// Node and edge annotations share the tag list of the node:
+	// the edge annotation having the key of a node annotation is not written
+	tr := parse(t, "((A[&a=1,k=node]:1[&k=edge,e=2],B),C);")
+	if exp := "((A:1[&&NHX:a=1:k=node:e=2],B),C);"; tr.NewickNHX() != exp {
+		t.Errorf("NewickNHX = %s, expected %s", tr.NewickNHX(), exp)
+	}
+	back := parse(t, tr.NewickNHX())
+	for _, tip := range back.Tips() {
+		if tip.Name() != "A" {
+			continue
+		}
+		if v, ok := tip.Annotation("e"); !ok || v.Number != 2 {
+			t.Errorf("Edge annotation e read back on the node: %v %v", v, ok)
+		}
+		if annots := tip.Edges()[0].Annotations(); len(annots) != 0 {
+			t.Errorf("Annotations of the edge read back: %v", annots)
+		}
+	}
+}
@@ -83,6 +83,11 @@ func (n *Node) AddComment(comment string) {
 	n.comment = append(n.comment, comment)
 }
 
+// Returns the comments of the node
+func (n *Node) GetComments() []string {
+	return n.comment
+}
+
 // Returns the string of comma separated comments
 // surounded by [].
 func (n *Node) CommentsString() string {
@@ -112,31 +117,88 @@ func (n *Node) Depth() (int, error) {
 	return n.depth, nil
 }
 
-// aggregate k=v pairs into the correct format for metadata comments:
-// AA={"S:L1234I", "...", ...}
+// Formats the comments of an edge. Annotation comments ([&key=value,...])
+// and comments of the form k=v (e.g. mutations) are merged into a single
+// annotation comment, the values of each k=v key being listed in order:
+// [&AA={"S:L1234I","S:D614G"},NUC={"C100T"}]. Other comments (e.g. NHX
+// comments) are written as is, each between [].
 func aggregateComments(comments []string) string {
-	m := make(map[string][]string)
-	for _, comment := range comments {
-		sa := strings.Split(comment, "=")
-		k := sa[0]
-		v := sa[1]
-		if _, ok := m[k]; ok {
-			m[k] = append(m[k], "\""+v+"\"")
-		} else {
-			m[k] = []string{"\"" + v + "\""}
+	// Index of the list of each k=v key in the merged annotations
+	lists := make(map[string]int)
+	return mergeComments(comments, func(annots []Annotation, c string) ([]Annotation, bool) {
+		k, v, ok := plainComment(c)
+		if !ok {
+			return annots, false
+		}
+		value := StringValue(v)
+		value.quoted = true
+		if i, ok := lists[k]; ok {
+			annots[i].Value.List = append(annots[i].Value.List, value)
+			return annots, true
+		}
+		lists[k] = len(annots)
+		return append(annots, Annotation{k, ListValue(value)}), true
+	})
+}
+
+// Formats the comments of a node. Annotation comments ([&key=value,...])
+// and comments of the form k=v are merged into a single annotation
+// comment: [&country=UK,date=2020]. Other comments (e.g. NHX comments)
+// are written as is, each between [].
+func joinComments(comments []string) string {
+	return mergeComments(comments, func(annots []Annotation, c string) ([]Annotation, bool) {
+		if a, ok := ParseAnnotations("&" + c); ok {
+			return append(annots, a...), true
+		}
+		k, v, ok := plainComment(c)
+		if !ok {
+			return annots, false
+		}
+		return append(annots, Annotation{k, StringValue(v)}), true
+	})
+}
+
+// Merges the annotation comments, and the other comments accepted by
+// plain, into a single annotation comment written in place of the first
+// merged comment. plain adds the annotations of a comment that does not
+// start with & to the merged annotations, and returns false if the
+// comment must be written as is.
+func mergeComments(comments []string, plain func(annots []Annotation, comment string) ([]Annotation, bool)) string {
+	parts := make([]string, 0, len(comments))
+	first := -1
+	annots := make([]Annotation, 0)
+	for _, c := range comments {
+		merged := false
+		if a, ok := ParseAnnotations(c); ok {
+			annots, merged = append(annots, a...), true
+		} else if !strings.HasPrefix(c, "&") {
+			annots, merged = plain(annots, c)
+		}
+		if !merged {
+			parts = append(parts, "["+c+"]")
+		} else if first == -1 {
+			first = len(parts)
+			parts = append(parts, "")
 		}
 	}
-	newstring := "&"
-	list := make([]string, 0)
-	for k, vs := range m {
-		temp := k + "={"
-		temp = temp + strings.Join(vs, ",")
-		temp = temp + "}"
-		list = append(list, temp)
+	if first != -1 {
+		parts[first] = "[" + formatAnnotations(annots) + "]"
 	}
-	newstring = newstring + strings.Join(list, ",")
+	return strings.Join(parts, "")
+}
 
-	return newstring
+// Splits a comment of the form key=value. Returns false if it has no
+// '=', or if the key cannot be written in an annotation comment.
+func plainComment(comment string) (key, value string, ok bool) {
+	i := strings.IndexByte(comment, '=')
+	if i < 0 {
+		return
+	}
+	key = strings.TrimSpace(comment[:i])
+	if key == "" || strings.ContainsAny(key, " \t\r\n,={}[]\"'&") {
+		return
+	}
+	return key, comment[i+1:], true
 }
 
 // Recursive function that outputs newick representation
@@ -171,14 +233,7 @@ func (n *Node) Newick(parent *Node, newick *bytes.Buffer) {
 					newick.WriteString(strconv.FormatFloat(n.br[i].length, 'f', -1, 64))
 				}
 				if len(n.br[i].comment) != 0 {
-					newick.WriteString("[")
 					newick.WriteString(aggregateComments(n.br[i].comment))
-					newick.WriteString("]")
-					// for _, c := range n.br[i].comment {
-					// 	newick.WriteString("[")
-					// 	newick.WriteString(c)
-					// 	newick.WriteString("]")
-					// }
 				}
 				nbchild++
 			}
@@ -187,7 +242,7 @@ func (n *Node) Newick(parent *Node, newick *bytes.Buffer) {
 			newick.WriteString(")")
 		}
 	}
-	newick.WriteString(n.name)
+	newick.WriteString(newickName(n.name, len(n.neigh) > 1))
 }
 
 func (n *Node) NewickOptionalComments(parent *Node, newick *bytes.Buffer, annotate_nodes bool, annotate_tips bool) {
@@ -210,14 +265,10 @@ func (n *Node) NewickOptionalComments(parent *Node, newick *bytes.Buffer, annota
 				}
 				if len(child.comment) != 0 {
 					if annotate_nodes && !child.Tip() {
-						newick.WriteString("[&")
-						newick.WriteString(strings.Join(child.comment, ","))
-						newick.WriteString("]")
+						newick.WriteString(joinComments(child.comment))
 					}
 					if annotate_tips && child.Tip() {
-						newick.WriteString("[&")
-						newick.WriteString(strings.Join(child.comment, ","))
-						newick.WriteString("]")
+						newick.WriteString(joinComments(child.comment))
 					}
 				}
 				if n.br[i].length != NIL_LENGTH {
@@ -225,9 +276,7 @@ func (n *Node) NewickOptionalComments(parent *Node, newick *bytes.Buffer, annota
 					newick.WriteString(strconv.FormatFloat(n.br[i].length, 'f', -1, 64))
 				}
 				if len(n.br[i].comment) != 0 {
-					newick.WriteString("[")
 					newick.WriteString(aggregateComments(n.br[i].comment))
-					newick.WriteString("]")
 				}
 				nbchild++
 			}
@@ -236,5 +285,59 @@ func (n *Node) NewickOptionalComments(parent *Node, newick *bytes.Buffer, annota
 			newick.WriteString(")")
 		}
 	}
-	newick.WriteString(n.name)
+	newick.WriteString(newickName(n.name, len(n.neigh) > 1))
+}
+
+// Replaces the neighbor old of the node by the neighbor n,
+// connected with edge e. Does nothing if old is not a neighbor.
+func (p *Node) replaceNeighbor(old *Node, n *Node, e *Edge) {
+	for i, c := range p.neigh {
+		if c == old {
+			p.neigh[i] = n
+			p.br[i] = e
+			return
+		}
+	}
+}
+
+// Removes the neighbor n of the node, and the edge connecting them.
+// Does nothing if n is not a neighbor.
+func (p *Node) delNeighbor(n *Node) {
+	for i, c := range p.neigh {
+		if c == n {
+			p.neigh = append(p.neigh[:i], p.neigh[i+1:]...)
+			p.br = append(p.br[:i], p.br[i+1:]...)
+			return
+		}
+	}
+}
+
+// Returns a copy of the node, without its neighbors. If shareStates
+// is true, the state slices are shared with the original node.
+func (n *Node) copy(shareStates bool) *Node {
+	c := &Node{
+		name:       n.name,
+		comment:    append(make([]string, 0, len(n.comment)), n.comment...),
+		neigh:      make([]*Node, 0, len(n.neigh)),
+		br:         make([]*Edge, 0, len(n.br)),
+		depth:      n.depth,
+		id:         n.id,
+		tipid:      n.tipid,
+		Upstates:   n.Upstates,
+		Downstates: n.Downstates,
+	}
+	if !shareStates {
+		c.Upstates = copyStates(n.Upstates)
+		c.Downstates = copyStates(n.Downstates)
+	}
+	return c
+}
+
+// Returns a deep copy of the states
+func copyStates(states [][]byte) [][]byte {
+	c := make([][]byte, len(states))
+	for i, s := range states {
+		c[i] = append(make([]byte, 0, len(s)), s...)
+	}
+	return c
 }
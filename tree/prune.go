@@ -0,0 +1,161 @@
+package tree
+
+import (
+	"errors"
+)
+
+// Removes the tips with the given names from the tree. If revert
+// is true, removes all the tips except the ones with the given names.
+//
+// Internal nodes left with only 2 neighbors are removed, and their
+// two edges are merged into one:
+//   - its length (and SynLen) is the sum of their lengths
+//   - its support is the one of the lower edge, if any, else the one of the upper edge
+//   - its comments are the comments of the upper edge followed by the
+//     comments of the lower edge, without duplicates
+//
+// Comments of such removed nodes are discarded, except for the root,
+// whose comments are given to the new root. If the tree was unrooted, it
+// stays unrooted, if it was rooted, it stays rooted.
+//
+// The tip index and the edge bitsets are recomputed.
+func (t *Tree) RemoveTips(names []string, revert bool) error {
+	tips := t.Tips()
+	byname := make(map[string][]*Node, len(tips))
+	for _, tip := range tips {
+		byname[tip.name] = append(byname[tip.name], tip)
+	}
+	selected := make(map[*Node]bool, len(names))
+	for _, name := range names {
+		nodes, ok := byname[name]
+		if !ok {
+			return errors.New("No tip named " + name + " in the tree")
+		}
+		for _, n := range nodes {
+			selected[n] = true
+		}
+	}
+	toremove := make([]*Node, 0, len(tips))
+	for _, tip := range tips {
+		if selected[tip] != revert {
+			toremove = append(toremove, tip)
+		}
+	}
+	if len(tips)-len(toremove) < 2 {
+		return errors.New("Cannot remove tips: less than 2 tips would remain in the tree")
+	}
+	for _, tip := range toremove {
+		if err := t.removeTip(tip); err != nil {
+			return err
+		}
+	}
+	return t.ReinitIndexes()
+}
+
+// Returns a new tree, being a copy of the clade below the given node.
+// The node must be an internal node of the tree; if it is the root, the
+// whole tree is copied. Branch lengths, supports, comments and states
+// are copied.
+//
+// The tip index and the edge bitsets of the new tree are computed.
+func (t *Tree) Subtree(n *Node) (*Tree, error) {
+	if n == nil {
+		return nil, errors.New("Cannot extract the subtree of a nil node")
+	}
+	if n.Tip() && n != t.root {
+		return nil, errors.New("Cannot extract the subtree of a tip")
+	}
+	var parent *Node = nil
+	if n != t.root {
+		for _, e := range n.br {
+			if e.right == n {
+				parent = e.left
+			}
+		}
+		if parent == nil {
+			return nil, errors.New("The node has no parent, edges may not be oriented from the root")
+		}
+	}
+	sub := NewTree()
+	sub.SetRoot(sub.copyNodeRecur(n, parent, false))
+	if err := sub.ReinitIndexes(); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// Removes the given tip and its edge from the tree, and removes the
+// internal nodes that are left with 2 neighbors.
+func (t *Tree) removeTip(tip *Node) error {
+	if !tip.Tip() {
+		return errors.New("Node " + tip.name + " is not a tip")
+	}
+	rooted := t.Rooted()
+	p := tip.neigh[0]
+	p.delNeighbor(tip)
+	tip.delNeighbor(p)
+	if tip == t.root {
+		t.root = p
+	} else if p != t.root {
+		if p.Nneigh() == 2 {
+			return t.collapseNode(p)
+		}
+		return nil
+	}
+	return t.collapseRoot(rooted)
+}
+
+// Removes the root if it is left with only 1 neighbor, which becomes
+// the root. If the tree was not rooted, the root is then removed if it
+// has 2 neighbors, as any other internal node, unless they are tips.
+func (t *Tree) collapseRoot(rooted bool) error {
+	for t.root.Nneigh() == 1 && !t.root.neigh[0].Tip() {
+		r, c := t.root, t.root.neigh[0]
+		c.delNeighbor(r)
+		c.comment = append(c.comment, r.comment...)
+		t.root = c
+	}
+	if !rooted && t.root.Nneigh() == 2 && (!t.root.neigh[0].Tip() || !t.root.neigh[1].Tip()) {
+		if _, _, err := t.unroot(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Removes the internal node having 2 neighbors, and merges its
+// upper edge into its lower edge.
+func (t *Tree) collapseNode(n *Node) error {
+	up, down := n.br[0], n.br[1]
+	if up.right != n {
+		up, down = down, up
+	}
+	if up.right != n || down.left != n {
+		return errors.New("Cannot remove node: edges are not oriented from the root")
+	}
+	q, c := up.left, down.right
+	down.merge(up)
+	down.setLeft(q)
+	q.replaceNeighbor(n, c, down)
+	c.replaceNeighbor(n, q, down)
+	return nil
+}
+
+// Recursively copies the node cur and all the nodes that are
+// reachable from it without going through prev, with their edges.
+// Returns the copy of cur. If shareStates is true, the state slices
+// are shared with the original nodes instead of being copied.
+func (t *Tree) copyNodeRecur(cur *Node, prev *Node, shareStates bool) *Node {
+	n := cur.copy(shareStates)
+	for i, child := range cur.neigh {
+		if child != prev {
+			c := t.copyNodeRecur(child, cur, shareStates)
+			e := cur.br[i].copy()
+			e.setLeft(n)
+			e.setRight(c)
+			n.addChild(c, e)
+			c.addChild(n, e)
+		}
+	}
+	return n
+}
@@ -0,0 +1,100 @@
+package tree_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/benjamincjackson/gotree/tree"
+)
+
+func TestRemoveTips(t *testing.T) {
+	unrooted := "((A:1,B:1)0.9:1,C:1,(D:1,E:1)0.8:1);"
+	rooted := "((A:1,B:1)0.9:1,(C:1,(D:1,E:1)0.8:1)0.7:1);"
+	tests := []struct {
+		nw       string
+		names    string
+		revert   bool
+		expected string // "" if an error is expected
+	}{
+		{unrooted, "A", false, "(B:2,C:1,(D:1,E:1)0.8:1);"},
+		{unrooted, "C", false, "((D:1,E:1)0.8:2,A:1,B:1);"},
+		{unrooted, "A,B", false, "(C:2,D:1,E:1);"},
+		{unrooted, "C,D,E", false, "(A:1,B:1);"},
+		{unrooted, "A,B", true, "(A:1,B:1);"},
+		{unrooted, "A,C,D", true, "(A:2,C:1,D:2);"},
+		{rooted, "C", false, "((A:1,B:1)0.9:1,(D:1,E:1)0.8:2);"},
+		{rooted, "A,B", false, "(C:1,(D:1,E:1)0.8:1);"},
+		{"(A:1,((B:1,C:1):1,(D:1,E:1):1):1);", "A", false, "((B:1,C:1):1,(D:1,E:1):1);"},
+		{unrooted, "X", false, ""},
+		{unrooted, "A,B,C,D", false, ""},
+		{unrooted, "A", true, ""},
+	}
+	for _, test := range tests {
+		tr := parse(t, test.nw)
+		err := tr.RemoveTips(strings.Split(test.names, ","), test.revert)
+		if test.expected == "" {
+			if err == nil {
+				t.Errorf("RemoveTips(%s, %s, %v) should return an error", test.nw, test.names, test.revert)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("RemoveTips(%s, %s, %v): %v", test.nw, test.names, test.revert, err)
+		} else if tr.Newick() != test.expected {
+			t.Errorf("RemoveTips(%s, %s, %v) = %s, expected %s", test.nw, test.names, test.revert, tr.Newick(), test.expected)
+		}
+	}
+}
+
+func TestRemoveRootTip(t *testing.T) {
+	// Unrooted tree whose root is the tip A: A-X, X-B, X-Y, Y-C, Y-D
+	tr := tree.NewTree()
+	nodes := make(map[string]*tree.Node)
+	for _, name := range []string{"A", "X", "B", "Y", "C", "D"} {
+		nodes[name] = tr.NewNode()
+		if name != "X" && name != "Y" {
+			nodes[name].SetName(name)
+		}
+	}
+	tr.SetRoot(nodes["A"])
+	for _, e := range [][2]string{{"A", "X"}, {"X", "B"}, {"X", "Y"}, {"Y", "C"}, {"Y", "D"}} {
+		tr.ConnectNodes(nodes[e[0]], nodes[e[1]]).SetLength(1)
+	}
+	if err := tr.RemoveTips([]string{"A"}, false); err != nil {
+		t.Fatal(err)
+	}
+	// X is left with 2 neighbors, and is removed
+	if exp := "(B:2,C:1,D:1);"; tr.Newick() != exp {
+		t.Errorf("RemoveTips(A) = %s, expected %s", tr.Newick(), exp)
+	}
+}
+
+func TestSubtree(t *testing.T) {
+	tr := parse(t, "((A:1,B:2)0.9:3[&x=1],C:4,(D:5,E:6)0.8:7);")
+	var clade *tree.Node
+	for _, e := range tr.Edges() {
+		if e.Support() == 0.8 {
+			clade = e.Right()
+		}
+	}
+	sub, err := tr.Subtree(clade)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "(D:5,E:6);"; sub.Newick() != exp {
+		t.Errorf("Subtree = %s, expected %s", sub.Newick(), exp)
+	}
+	if _, err := sub.TipIndex("D"); err != nil {
+		t.Errorf("Tip index of the subtree: %v", err)
+	}
+	// The original tree is not modified
+	if exp := "((A:1,B:2)0.9:3[&x=1],C:4,(D:5,E:6)0.8:7);"; tr.Newick() != exp {
+		t.Errorf("Tree after Subtree = %s, expected %s", tr.Newick(), exp)
+	}
+	if whole, err := tr.Subtree(tr.Root()); err != nil || whole.Newick() != tr.Newick() {
+		t.Errorf("Subtree(root) = %s (%v)", whole.Newick(), err)
+	}
+	if _, err := tr.Subtree(tr.Tips()[0]); err == nil {
+		t.Errorf("Subtree of a tip should return an error")
+	}
+}
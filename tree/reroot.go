@@ -0,0 +1,294 @@
+package tree
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Reroots the tree on the given edge: a new root node is
+// inserted in the middle of the edge, and becomes the root of
+// the tree. Both new edges have the support of the original edge,
+// its comments are kept on the lower one.
+//
+// If the tree is rooted, it is first unrooted.
+// Edge bitsets are not updated, it may be useful to call
+//
+//	t.ReinitIndexes()
+//
+// afterwards.
+func (t *Tree) RerootOnEdge(e *Edge) error {
+	removed, kept, err := t.unroot()
+	if err != nil {
+		return err
+	}
+	if e == removed {
+		e = kept
+	}
+	length := e.length
+	if length != NIL_LENGTH {
+		length /= 2.0
+	}
+	return t.rerootOnEdgeAt(e, length)
+}
+
+// Reroots the tree on the edge leading to the most recent
+// common ancestor of the given tips, in a tree rooted on any
+// tip not being part of the outgroup. If the outgroup is
+// monophyletic, the new root separates it from the other tips.
+//
+// If the tree is rooted, it is first unrooted.
+// Edge bitsets are not updated, it may be useful to call
+//
+//	t.ReinitIndexes()
+//
+// afterwards.
+func (t *Tree) RerootOutgroup(tipNames ...string) error {
+	if len(tipNames) == 0 {
+		return errors.New("No outgroup tips given")
+	}
+	tips := t.Tips()
+	outgroup := make(map[*Node]bool, len(tipNames))
+	byname := make(map[string]*Node, len(tips))
+	for _, tip := range tips {
+		byname[tip.name] = tip
+	}
+	for _, name := range tipNames {
+		tip, ok := byname[name]
+		if !ok {
+			return errors.New("No tip named " + name + " in the tree")
+		}
+		outgroup[tip] = true
+	}
+	var ingroup *Node = nil
+	for _, tip := range tips {
+		if !outgroup[tip] {
+			ingroup = tip
+			break
+		}
+	}
+	if ingroup == nil {
+		return errors.New("All the tips of the tree are in the outgroup")
+	}
+	if _, _, err := t.unroot(); err != nil {
+		return err
+	}
+
+	// Parent node and edge of each node, when the tree
+	// is temporarily rooted on the ingroup tip
+	parents := make(map[*Node]*Node)
+	parentEdges := make(map[*Node]*Edge)
+	t.parentsRecur(ingroup, nil, nil, parents, parentEdges)
+
+	// MRCA of the outgroup tips: mark the path from the first tip
+	// to the top, then walk up from each other tip to this path
+	path := make(map[*Node]int)
+	first := byname[tipNames[0]]
+	depth := 0
+	for n := first; n != nil; n = parents[n] {
+		path[n] = depth
+		depth++
+	}
+	mrca := first
+	for _, name := range tipNames[1:] {
+		n := byname[name]
+		for {
+			if d, ok := path[n]; ok {
+				if d > path[mrca] {
+					mrca = n
+				}
+				break
+			}
+			n = parents[n]
+		}
+	}
+	e := parentEdges[mrca]
+	length := e.length
+	if length != NIL_LENGTH {
+		length /= 2.0
+	}
+	return t.rerootOnEdgeAt(e, length)
+}
+
+// Reroots the tree at the middle of the longest path between
+// two tips. All the branches must have a length.
+//
+// If the tree is rooted, it is first unrooted.
+// Edge bitsets are not updated, it may be useful to call
+//
+//	t.ReinitIndexes()
+//
+// afterwards.
+func (t *Tree) RerootMidpoint() error {
+	for _, e := range t.Edges() {
+		if e.length == NIL_LENGTH {
+			return errors.New("Cannot reroot at midpoint: some branches have no length")
+		}
+	}
+	if _, _, err := t.unroot(); err != nil {
+		return err
+	}
+	tips := t.Tips()
+	if len(tips) < 2 {
+		return errors.New("Cannot reroot at midpoint a tree with less than 2 tips")
+	}
+	// The farthest tip from any tip is one end of the longest path
+	a, _ := t.farthestTip(tips[0])
+	b, dist := t.farthestTip(a)
+
+	parents := make(map[*Node]*Node)
+	parentEdges := make(map[*Node]*Edge)
+	t.parentsRecur(a, nil, nil, parents, parentEdges)
+
+	// Walk up from b towards a, until the midpoint is reached
+	half := dist / 2.0
+	cur := 0.0
+	for n := b; parents[n] != nil; n = parents[n] {
+		e := parentEdges[n]
+		if cur+e.length >= half {
+			// Distance of the midpoint from the lower node n
+			fromlower := half - cur
+			if e.right == n {
+				return t.rerootOnEdgeAt(e, e.length-fromlower)
+			}
+			return t.rerootOnEdgeAt(e, fromlower)
+		}
+		cur += e.length
+	}
+	return fmt.Errorf("Cannot find the midpoint of the tree")
+}
+
+// Unroots the tree: if the root node has 2 neighbors, it is removed,
+// and its two edges are merged into one, whose length is the sum of
+// their lengths. One of the two neighbors that is not a tip becomes
+// the new root. Does nothing if the tree is not rooted.
+//
+// Edge bitsets are not updated, it may be useful to call
+//
+//	t.ReinitIndexes()
+//
+// afterwards.
+func (t *Tree) Unroot() error {
+	_, _, err := t.unroot()
+	return err
+}
+
+// Unroots the tree and returns the edge that has been removed
+// and the one in which it has been merged, if the tree was rooted.
+func (t *Tree) unroot() (removed, kept *Edge, err error) {
+	if t.root == nil || t.root.Nneigh() != 2 {
+		return
+	}
+	r := t.root
+	a, b := r.neigh[0], r.neigh[1]
+	ea, eb := r.br[0], r.br[1]
+	if a.Tip() {
+		if b.Tip() {
+			err = errors.New("Cannot unroot a tree with only 2 tips")
+			return
+		}
+		a, b = b, a
+		ea, eb = eb, ea
+	}
+	// a becomes the root, eb connects a and b
+	eb.merge(ea)
+	a.replaceNeighbor(r, b, eb)
+	b.replaceNeighbor(r, a, eb)
+	a.comment = append(a.comment, r.comment...)
+	t.root = a
+	t.reorderEdges(a, nil)
+	removed, kept = ea, eb
+	return
+}
+
+// Inserts a new root node in the given edge, at the given distance
+// from its left node, and reorients all the edges of the tree.
+// The tree must be unrooted.
+//
+// The edge is split in two: the part between the root and the right
+// node keeps the edge, and the part between the root and the left node
+// is a new edge, with the same support and pvalue. If the edges of the
+// tree have ids, the new edge is given a new id, larger than the others.
+func (t *Tree) rerootOnEdgeAt(e *Edge, fromleft float64) error {
+	if e == nil {
+		return errors.New("Cannot reroot on a nil edge")
+	}
+	left, right := e.left, e.right
+	root := t.NewNode()
+	newedge := t.NewEdge()
+	newedge.support = e.support
+	newedge.pvalue = e.pvalue
+	if e.id != NIL_ID {
+		for _, other := range t.Edges() {
+			if other.id >= newedge.id {
+				newedge.id = other.id + 1
+			}
+		}
+	}
+	if e.length != NIL_LENGTH {
+		newedge.length = fromleft
+		e.length = e.length - fromleft
+	}
+	// e now connects root and right, newedge connects root and left
+	left.replaceNeighbor(right, root, newedge)
+	right.replaceNeighbor(left, root, e)
+	root.addChild(left, newedge)
+	root.addChild(right, e)
+	t.root = root
+	t.reorderEdges(root, nil)
+	return nil
+}
+
+// Recursively orients the edges of the tree from cur to its
+// neighbors other than prev: cur becomes their left node.
+func (t *Tree) reorderEdges(cur *Node, prev *Node) {
+	for i, n := range cur.neigh {
+		if n != prev {
+			cur.br[i].setLeft(cur)
+			cur.br[i].setRight(n)
+			t.reorderEdges(n, cur)
+		}
+	}
+}
+
+// Recursively fills the parent node and the parent edge of each node,
+// as if the tree was rooted on the starting node
+func (t *Tree) parentsRecur(cur *Node, prev *Node, e *Edge, parents map[*Node]*Node, edges map[*Node]*Edge) {
+	parents[cur] = prev
+	edges[cur] = e
+	for i, n := range cur.neigh {
+		if n != prev {
+			t.parentsRecur(n, cur, cur.br[i], parents, edges)
+		}
+	}
+}
+
+// Returns the tip that is the farthest from the given node, and
+// its distance to it
+func (t *Tree) farthestTip(from *Node) (far *Node, dist float64) {
+	far, dist = from, 0.0
+	t.farthestTipRecur(from, nil, 0.0, &far, &dist)
+	return
+}
+
+func (t *Tree) farthestTipRecur(cur *Node, prev *Node, curdist float64, far **Node, maxdist *float64) {
+	if cur.Tip() && curdist > *maxdist {
+		*far = cur
+		*maxdist = curdist
+	}
+	for i, n := range cur.neigh {
+		if n != prev {
+			t.farthestTipRecur(n, cur, curdist+cur.br[i].length, far, maxdist)
+		}
+	}
+}
+
+// Sums two branch lengths, taking into account undefined lengths
+func sumLengths(l1, l2 float64) float64 {
+	if l1 == NIL_LENGTH {
+		return l2
+	}
+	if l2 == NIL_LENGTH {
+		return l1
+	}
+	return l1 + l2
+}
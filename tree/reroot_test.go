@@ -0,0 +1,90 @@
+package tree_test
+
+import (
+	"testing"
+
+	"github.com/benjamincjackson/gotree/tree"
+)
+
+func TestReroot(t *testing.T) {
+	unrooted := "((A:1,B:2)0.9:3,C:4,(D:5,E:6)0.8:7);"
+	rooted := "((A:1,B:2)0.9:3,(C:4,(D:5,E:6)0.8:7):2);"
+	tests := []struct {
+		name     string
+		nw       string
+		reroot   func(*tree.Tree) error
+		expected string // "" if an error is expected
+	}{
+		{"outgroup DE", unrooted, func(tr *tree.Tree) error { return tr.RerootOutgroup("D", "E") },
+			"(((A:1,B:2)0.9:3,C:4)0.8:3.5,(D:5,E:6)0.8:3.5);"},
+		{"outgroup C", unrooted, func(tr *tree.Tree) error { return tr.RerootOutgroup("C") },
+			"(((A:1,B:2)0.9:3,(D:5,E:6)0.8:7):2,C:2);"},
+		{"outgroup rooted", rooted, func(tr *tree.Tree) error { return tr.RerootOutgroup("A", "B") },
+			"((A:1,B:2)0.9:2.5,(C:4,(D:5,E:6)0.8:7)0.9:2.5);"},
+		{"outgroup missing", unrooted, func(tr *tree.Tree) error { return tr.RerootOutgroup("X") }, ""},
+		// Longest path: E-B (18), its middle is at 3 from the DE node
+		{"midpoint", unrooted, func(tr *tree.Tree) error { return tr.RerootMidpoint() },
+			"(((A:1,B:2)0.9:3,C:4)0.8:4,(D:5,E:6)0.8:3);"},
+		{"midpoint rooted", rooted, func(tr *tree.Tree) error { return tr.RerootMidpoint() },
+			"(((A:1,B:2)0.9:5,C:4)0.8:3,(D:5,E:6)0.8:4);"},
+		{"midpoint no length", "((A:1,B:2)0.9:3,C,(D:5,E:6)0.8:7);", func(tr *tree.Tree) error { return tr.RerootMidpoint() }, ""},
+		{"unroot", rooted, func(tr *tree.Tree) error { return tr.Unroot() },
+			"((C:4,(D:5,E:6)0.8:7)0.9:5,A:1,B:2);"},
+		{"unroot unrooted", unrooted, func(tr *tree.Tree) error { return tr.Unroot() }, unrooted},
+	}
+	for _, test := range tests {
+		tr := parse(t, test.nw)
+		err := test.reroot(tr)
+		if test.expected == "" {
+			if err == nil {
+				t.Errorf("%s: should return an error, gives %s", test.name, tr.Newick())
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		} else if tr.Newick() != test.expected {
+			t.Errorf("%s: %s, expected %s", test.name, tr.Newick(), test.expected)
+		}
+	}
+}
+
+func TestRerootOnEdge(t *testing.T) {
+	tr := parse(t, "((A:1,B:2)0.9:3,C:4,(D:5,E:6)0.8:7);")
+	var edge *tree.Edge
+	for _, e := range tr.Edges() {
+		if e.Right().Name() == "B" {
+			edge = e
+		}
+	}
+	if err := tr.RerootOnEdge(edge); err != nil {
+		t.Fatal(err)
+	}
+	if exp := "(((C:4,(D:5,E:6)0.8:7)0.9:3,A:1):1,B:1);"; tr.Newick() != exp {
+		t.Errorf("RerootOnEdge: %s, expected %s", tr.Newick(), exp)
+	}
+	if !tr.Rooted() {
+		t.Errorf("Tree should be rooted")
+	}
+	// The tree still has the same bipartitions
+	if rf, err := tree.RobinsonFoulds(tr, parse(t, "((A,B),C,(D,E));"), false); err != nil || rf != 0 {
+		t.Errorf("RobinsonFoulds after rerooting: %d (%v)", rf, err)
+	}
+}
+
+func TestRerootEdgeIds(t *testing.T) {
+	tr := parse(t, "((A:1,B:2)0.9:3,C:4,(D:5,E:6)0.8:7);")
+	if err := tr.RerootOutgroup("D", "E"); err != nil {
+		t.Fatal(err)
+	}
+	ids := make(map[int]bool)
+	for _, e := range tr.Edges() {
+		if e.Id() == tree.NIL_ID || ids[e.Id()] {
+			t.Errorf("Edge above %s has id %d, already used or nil", e.Right().Name(), e.Id())
+		}
+		ids[e.Id()] = true
+	}
+	if len(ids) != 8 {
+		t.Errorf("%d edge ids, expected 8", len(ids))
+	}
+}
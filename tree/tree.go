@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"sort"
 	"strconv"
 	"strings"
+
+	fbitset "github.com/fredericlemoine/bitset"
 )
 
 // Tree structure having a root and a tip index, that maps tip names to their index
@@ -23,6 +26,27 @@ func NewTree() *Tree {
 	}
 }
 
+// Returns a deep copy of the tree. Node, tip and edge ids, branch
+// lengths, SynLen, supports, pvalues, comments, states, edge bitsets
+// and the tip index are copied.
+//
+// If shareStates is true, the Upstates and Downstates slices of
+// the nodes are shared with the original tree instead of being
+// copied, to save memory. They should then not be modified in place.
+func (t *Tree) Clone(shareStates bool) *Tree {
+	c := NewTree()
+	if t.root == nil {
+		return c
+	}
+	c.SetRoot(c.copyNodeRecur(t.root, nil, shareStates))
+	for _, tip := range c.Tips() {
+		if _, ok := t.tipIndex[tip.name]; ok {
+			c.tipIndex[tip.name] = tip
+		}
+	}
+	return c
+}
+
 // TEMPORARY:
 func (t *Tree) PrintMap() {
 	for k, v := range t.tipIndex {
@@ -78,6 +102,93 @@ func (t *Tree) UpdateTipIndex() (err error) {
 	return
 }
 
+// Clears the bitsets, hashcodes and numbers of taxa
+// of all the edges of the tree
+func (t *Tree) ClearBitSets() {
+	for _, e := range t.Edges() {
+		e.bitset = nil
+		e.hashcodeleft = 0
+		e.hashcoderight = 0
+		e.ntaxleft = 0
+		e.ntaxright = 0
+	}
+}
+
+// Updates the bitsets, hashcodes and numbers of taxa of
+// all the edges of the tree.
+//
+// Assumes that the tip index is initialized with
+// UpdateTipIndex, and that edges are oriented from
+// the root (left) to the tips (right).
+func (t *Tree) UpdateBitSet() error {
+	if len(t.tipIndex) == 0 {
+		return errors.New("No tips in the index, tip name index is not initialized")
+	}
+	var total uint64 = 0
+	for name := range t.tipIndex {
+		total += tipHashCode(name)
+	}
+	_, _, err := t.updateBitSetRecur(t.root, nil, nil, uint(len(t.tipIndex)), total)
+	return err
+}
+
+// Recursive function that fills the bitset of the edge e leading to cur.
+// Returns the bitset of the tips below cur, and their hashcode.
+func (t *Tree) updateBitSetRecur(cur *Node, prev *Node, e *Edge, ntips uint, total uint64) (bs *fbitset.BitSet, hash uint64, err error) {
+	bs = fbitset.New(ntips)
+	if cur.Tip() && prev != nil {
+		tip, ok := t.tipIndex[cur.name]
+		if !ok || tip != cur {
+			err = errors.New("Tip " + cur.name + " is not in the tip index, it should be updated")
+			return
+		}
+		bs.Set(uint(cur.tipid))
+		hash = tipHashCode(cur.name)
+	} else {
+		for i, n := range cur.neigh {
+			if n != prev {
+				var childbs *fbitset.BitSet
+				var childhash uint64
+				if childbs, childhash, err = t.updateBitSetRecur(n, cur, cur.br[i], ntips, total); err != nil {
+					return
+				}
+				bs.InPlaceUnion(childbs)
+				hash += childhash
+			}
+		}
+	}
+	if e != nil {
+		e.bitset = bs
+		e.hashcoderight = hash
+		e.hashcodeleft = total - hash
+		e.ntaxright = int(bs.Count())
+		e.ntaxleft = int(ntips) - e.ntaxright
+	}
+	return
+}
+
+// Clears and recomputes the tip index, and the bitsets,
+// hashcodes and numbers of taxa of all the edges.
+//
+// It should be called after any modification of the
+// topology of the tree, or of its tip names.
+func (t *Tree) ReinitIndexes() (err error) {
+	if err = t.UpdateTipIndex(); err != nil {
+		return
+	}
+	t.ClearBitSets()
+	return t.UpdateBitSet()
+}
+
+// Hashcode of a tip, computed from its name. The hashcode
+// of a set of tips is the sum of the hashcodes of its tips,
+// so that it does not depend on the tip index of the tree.
+func tipHashCode(name string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return h.Sum64()
+}
+
 /* Tips, sorted by their order in the bitsets*/
 func (t *Tree) SortedTips() []*Node {
 	tips := t.Tips()
@@ -335,6 +446,31 @@ func (t *Tree) SortNeighborsByTips(cur, prev *Node) int {
 	}
 }
 
+// Sorts the neighbors of cur using the less function (stable sort).
+// The neighbor prev (e.g. the parent of cur) keeps its position.
+// The neighbors of the other nodes are not sorted.
+func (t *Tree) SortNeighbors(cur, prev *Node, less func(n1, n2 *Node) bool) {
+	indices := make([]int, 0, len(cur.neigh))
+	neighbors := make([]struct {
+		neigh *Node
+		br    *Edge
+	}, 0, len(cur.neigh))
+	for i, n := range cur.neigh {
+		if n != prev {
+			indices = append(indices, i)
+			neighbors = append(neighbors, struct {
+				neigh *Node
+				br    *Edge
+			}{n, cur.br[i]})
+		}
+	}
+	sort.SliceStable(neighbors, func(i, j int) bool { return less(neighbors[i].neigh, neighbors[j].neigh) })
+	for i, idx := range indices {
+		cur.neigh[idx] = neighbors[i].neigh
+		cur.br[idx] = neighbors[i].br
+	}
+}
+
 // Ben edit:
 func (t *Tree) NexusOptionalComments(annotate_nodes bool, annotate_tips bool) string {
 	newick := t.NewickOptionalComments(annotate_nodes, annotate_tips)
@@ -347,7 +483,7 @@ func (t *Tree) NexusOptionalComments(annotate_nodes bool, annotate_tips bool) st
 	buffer.WriteString(";\n")
 	buffer.WriteString(" TAXLABELS")
 	for _, tip := range tips {
-		buffer.WriteString(" " + tip.Name())
+		buffer.WriteString(" " + newickName(tip.Name(), false))
 	}
 	buffer.WriteString(";\n")
 	buffer.WriteString("END;\n")
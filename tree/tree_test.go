@@ -0,0 +1,203 @@
+package tree_test
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/benjamincjackson/gotree/newick"
+	"github.com/benjamincjackson/gotree/tree"
+)
+
+// Parses the newick string, failing the test on error
+func parse(t *testing.T, nw string) *tree.Tree {
+	t.Helper()
+	tr, err := newick.NewParser(strings.NewReader(nw)).Parse()
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", nw, err)
+	}
+	return tr
+}
+
+// Parses the newick string and computes its indexes
+func parseIndexed(t *testing.T, nw string) *tree.Tree {
+	t.Helper()
+	tr := parse(t, nw)
+	if err := tr.ReinitIndexes(); err != nil {
+		t.Fatalf("ReinitIndexes(%q): %v", nw, err)
+	}
+	return tr
+}
+
+// Sorted names of the tips below the edge
+func tipsBelow(tr *tree.Tree, e *tree.Edge) string {
+	names := make([]string, 0)
+	for _, name := range tr.AllTipNames() {
+		id, _ := tr.TipIndex(name)
+		if e.TipPresent(uint(id)) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// Sorted names of the tips on the side of the edge not containing
+// the first tip name in alphabetical order
+func bipartition(tr *tree.Tree, e *tree.Edge) string {
+	names := tr.AllTipNames()
+	sort.Strings(names)
+	side := make([]string, 0)
+	first, _ := tr.TipIndex(names[0])
+	for _, name := range names {
+		id, _ := tr.TipIndex(name)
+		if e.TipPresent(uint(id)) != e.TipPresent(uint(first)) {
+			side = append(side, name)
+		}
+	}
+	return strings.Join(side, ",")
+}
+
+func TestUpdateBitSet(t *testing.T) {
+	tests := []struct {
+		nw    string
+		edges map[string][2]int // tips below the edge -> number of tips right and left
+	}{
+		{"((A,B),C,(D,E));", map[string][2]int{
+			"A": {1, 4}, "B": {1, 4}, "C": {1, 4}, "D": {1, 4}, "E": {1, 4},
+			"A,B": {2, 3}, "D,E": {2, 3},
+		}},
+		{"((A,B),(C,(D,E)));", map[string][2]int{
+			"A": {1, 4}, "B": {1, 4}, "C": {1, 4}, "D": {1, 4}, "E": {1, 4},
+			"A,B": {2, 3}, "D,E": {2, 3}, "C,D,E": {3, 2},
+		}},
+	}
+	for _, test := range tests {
+		tr := parseIndexed(t, test.nw)
+		edges := tr.Edges()
+		if len(edges) != len(test.edges) {
+			t.Errorf("%s: %d edges, expected %d", test.nw, len(edges), len(test.edges))
+		}
+		for _, e := range edges {
+			below := tipsBelow(tr, e)
+			exp, ok := test.edges[below]
+			if !ok {
+				t.Errorf("%s: unexpected edge above %s", test.nw, below)
+				continue
+			}
+			if e.NumTipsRight() != exp[0] || e.NumTipsLeft() != exp[1] {
+				t.Errorf("%s: edge above %s has %d/%d tips, expected %d/%d",
+					test.nw, below, e.NumTipsRight(), e.NumTipsLeft(), exp[0], exp[1])
+			}
+			if trivial := exp[0] == 1 || exp[1] == 1; e.Trivial() != trivial {
+				t.Errorf("%s: edge above %s: Trivial()=%v", test.nw, below, e.Trivial())
+			}
+		}
+	}
+}
+
+func TestHashCode(t *testing.T) {
+	// Same tips in different orders: AB|CDE is the only common internal
+	// bipartition, on edges having different orientations
+	t1 := parseIndexed(t, "((A,B),C,(D,E));")
+	t2 := parseIndexed(t, "((E,C),(B,A),D);")
+	hashes := func(tr *tree.Tree) map[uint64]string {
+		h := make(map[uint64]string)
+		for _, e := range tr.Edges() {
+			if _, ok := h[e.HashCode()]; ok {
+				t.Errorf("Two edges with the same hashcode in %s", tr.Newick())
+			}
+			h[e.HashCode()] = bipartition(tr, e)
+		}
+		return h
+	}
+	h1, h2 := hashes(t1), hashes(t2)
+	common := 0
+	for h, b1 := range h1 {
+		if b2, ok := h2[h]; ok {
+			common++
+			if b1 != b2 {
+				t.Errorf("Bipartitions %s and %s share a hashcode", b1, b2)
+			}
+		}
+	}
+	// 5 tip edges and AB|CDE
+	if common != 6 {
+		t.Errorf("%d common hashcodes, expected 6", common)
+	}
+	t1.ClearBitSets()
+	if e := t1.Edges()[0]; e.HashCode() != 0 || e.Bitset() != nil {
+		t.Errorf("Hashcode or bitset not cleared")
+	}
+}
+
+func TestUpdateBitSetWithoutIndex(t *testing.T) {
+	tr := parse(t, "((A,B),C,(D,E));")
+	if err := tr.UpdateBitSet(); err == nil {
+		t.Errorf("UpdateBitSet without tip index should return an error")
+	}
+}
+
+func TestClone(t *testing.T) {
+	tr := parseIndexed(t, "((A:1,B:2)0.9:3[&x=1],C:4[c],(D:5,E:6)0.8:7)[r];")
+	for _, tip := range tr.Tips() {
+		tip.SetUpstates([][]byte{{'A', 'C'}})
+	}
+	for _, share := range []bool{false, true} {
+		c := tr.Clone(share)
+		if c.Newick() != tr.Newick() {
+			t.Errorf("Clone(%v) = %s, expected %s", share, c.Newick(), tr.Newick())
+		}
+		if _, err := c.TipIndex("E"); err != nil {
+			t.Errorf("Clone(%v): tip index not copied: %v", share, err)
+		}
+		// Bitsets are copied
+		if rf, err := tree.RobinsonFoulds(c, tr, false); err != nil || rf != 0 {
+			t.Errorf("Clone(%v): RF with the original tree: %d (%v)", share, rf, err)
+		}
+		c.Tips()[0].Upstates[0][0] = 'T'
+		if shared := tr.Tips()[0].Upstates[0][0] == 'T'; shared != share {
+			t.Errorf("Clone(%v): states shared: %v", share, shared)
+		}
+		tr.Tips()[0].Upstates[0][0] = 'A'
+		// Modifying the clone does not modify the original tree
+		c.Edges()[0].SetLength(10)
+		c.Root().AddComment("new")
+		if err := c.RemoveTips([]string{"A"}, false); err != nil {
+			t.Fatal(err)
+		}
+		if exp := "((A:1,B:2)0.9:3[&x=1],C:4[c],(D:5,E:6)0.8:7)[r];"; tr.Newick() != exp {
+			t.Errorf("Original tree modified by its clone: %s", tr.Newick())
+		}
+	}
+}
+
+func TestNewickQuotedNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		internal bool
+		expected string
+	}{
+		{"A", false, "((A,Y),Z);"},
+		{"A B", false, "(('A B',Y),Z);"},
+		{"A_B", false, "(('A_B',Y),Z);"},
+		{"O'Brien", false, "(('O''Brien',Y),Z);"},
+		{"a(b):c;d,e[f]", false, "(('a(b):c;d,e[f]',Y),Z);"},
+		{"A\u00a0", false, "(('A\u00a0',Y),Z);"},
+		{"0.9", false, "((0.9,Y),Z);"},
+		{"0.9", true, "((X,Y)'0.9',Z);"},
+		{"0.9/0.01", true, "((X,Y)'0.9/0.01',Z);"},
+		{"A/B", true, "((X,Y)A/B,Z);"},
+	}
+	for _, test := range tests {
+		tr := parse(t, "((X,Y),Z);")
+		for _, n := range tr.Nodes() {
+			if (test.internal && !n.Tip() && n != tr.Root()) || (!test.internal && n.Name() == "X") {
+				n.SetName(test.name)
+			}
+		}
+		if tr.Newick() != test.expected {
+			t.Errorf("Name %q written %s, expected %s", test.name, tr.Newick(), test.expected)
+		}
+	}
+}